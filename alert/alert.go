@@ -0,0 +1,77 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+func (s Severity) Valid() bool {
+	_, ok := severityRanks[s]
+	return ok
+}
+
+type Event struct {
+	Key      string
+	Name     string
+	Currency string
+	Rate     request.Rate
+	Severity Severity
+	Message  string
+	Time     time.Time
+	Resolved bool
+	Labels   map[string]string
+	Values   map[string]float64
+}
+
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	str := fmt.Sprintf("alert name=%v key=%v currency=%v date=%v price=%v severity=%v resolved=%v message=%q\n",
+		e.Name, e.Key, e.Currency, e.Rate.Date.Format(time.DateOnly), e.Rate.Value, e.Severity, e.Resolved, e.Message)
+	n, err := io.WriteString(w, str)
+	return int64(n), err
+}
+
+//go:generate mockery --name=Sink --case underscore --with-expecter
+type Sink interface {
+	Notify(ctx context.Context, events []Event) error
+}
+
+type WriterSink struct {
+	out io.Writer
+}
+
+func NewWriterSink(out io.Writer) WriterSink {
+	return WriterSink{
+		out: out,
+	}
+}
+
+func (s WriterSink) Notify(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		if _, err := e.WriteTo(s.out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
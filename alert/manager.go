@@ -0,0 +1,371 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/koenno/currency-price-monitor/state"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	defaultCooldown = time.Hour
+
+	managerStateKey = "alert/manager"
+)
+
+var (
+	ErrInvalidSilence  = errors.New("invalid silence")
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+type Silence struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Currency string            `json:"currency,omitempty"`
+	Key      string            `json:"key,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Comment  string            `json:"comment,omitempty"`
+	Expires  time.Time         `json:"expires"`
+}
+
+func (s Silence) matches(e Event) bool {
+	if s.Name != "" && s.Name != e.Name {
+		return false
+	}
+	if s.Currency != "" && s.Currency != e.Currency {
+		return false
+	}
+	if s.Key != "" && s.Key != e.Key {
+		return false
+	}
+	for k, v := range s.Labels {
+		if e.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+type managerOptions struct {
+	cooldown  time.Duration
+	groupWait time.Duration
+	store     state.Store
+}
+
+type ManagerOption func(*managerOptions)
+
+func WithCooldown(cooldown time.Duration) ManagerOption {
+	return func(o *managerOptions) {
+		o.cooldown = cooldown
+	}
+}
+
+func WithGroupWait(wait time.Duration) ManagerOption {
+	return func(o *managerOptions) {
+		o.groupWait = wait
+	}
+}
+
+func WithManagerStore(store state.Store) ManagerOption {
+	return func(o *managerOptions) {
+		o.store = store
+	}
+}
+
+type keyState struct {
+	Severity Severity  `json:"severity"`
+	Resolved bool      `json:"resolved"`
+	Sent     time.Time `json:"sent"`
+}
+
+type managerState struct {
+	Keys     map[string]keyState `json:"keys"`
+	Silences []Silence           `json:"silences"`
+}
+
+// admission is an event let through together with the state of its key
+// before, so the state can be rolled back when the event is not delivered.
+type admission struct {
+	event    Event
+	state    keyState
+	previous keyState
+	known    bool
+}
+
+// Manager sits between alert producers and sinks. It drops repeated alerts
+// of the same key within a cooldown, groups bursts and applies silences.
+type Manager struct {
+	next    Sink
+	options managerOptions
+	now     func() time.Time
+
+	mtx    sync.Mutex
+	states map[string]keyState
+	// delivered holds the states of keys whose alerts reached next; only
+	// those are persisted.
+	delivered map[string]keyState
+	silences  map[string]Silence
+	pending   []admission
+	timer     *time.Timer
+}
+
+func NewManager(next Sink, opts ...ManagerOption) (*Manager, error) {
+	cfg := managerOptions{
+		cooldown: defaultCooldown,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	m := &Manager{
+		next:      next,
+		options:   cfg,
+		now:       time.Now,
+		states:    make(map[string]keyState),
+		delivered: make(map[string]keyState),
+		silences:  make(map[string]Silence),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Manager) Notify(ctx context.Context, events []Event) error {
+	m.mtx.Lock()
+	now := m.now()
+	m.pruneSilences(now)
+	var admitted []admission
+	for _, e := range events {
+		if a, ok := m.admit(e, now); ok {
+			admitted = append(admitted, a)
+		}
+	}
+	if len(admitted) == 0 {
+		m.mtx.Unlock()
+		return nil
+	}
+	if m.options.groupWait <= 0 {
+		m.mtx.Unlock()
+		return m.deliver(ctx, admitted)
+	}
+	for _, a := range admitted {
+		m.enqueue(a)
+	}
+	if m.timer == nil && len(m.pending) > 0 {
+		m.timer = time.AfterFunc(m.options.groupWait, func() {
+			if err := m.Flush(context.Background()); err != nil {
+				slog.Error("failed to send grouped alerts", "error", err)
+			}
+		})
+	}
+	m.mtx.Unlock()
+	return nil
+}
+
+func (m *Manager) Flush(ctx context.Context) error {
+	m.mtx.Lock()
+	admitted := m.pending
+	m.pending = nil
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.mtx.Unlock()
+
+	if len(admitted) == 0 {
+		return nil
+	}
+	return m.deliver(ctx, admitted)
+}
+
+// deliver passes the admitted events to next. The states of their keys are
+// persisted once next accepted them and rolled back when it failed, so the
+// alerts are let through again.
+func (m *Manager) deliver(ctx context.Context, admitted []admission) error {
+	events := make([]Event, 0, len(admitted))
+	for _, a := range admitted {
+		events = append(events, a.event)
+	}
+	err := m.next.Notify(ctx, events)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err != nil {
+		for _, a := range admitted {
+			if m.states[a.event.Key] == a.state {
+				m.restore(a)
+			}
+		}
+		return err
+	}
+	for _, a := range admitted {
+		m.delivered[a.event.Key] = a.state
+	}
+	return m.save()
+}
+
+func (m *Manager) admit(e Event, now time.Time) (admission, bool) {
+	for _, s := range m.silences {
+		if s.matches(e) {
+			return admission{}, false
+		}
+	}
+
+	previous, known := m.states[e.Key]
+	switch {
+	case !known && e.Resolved:
+		return admission{}, false
+	case known && previous.Resolved != e.Resolved:
+	case known && !e.Resolved && e.Severity.Rank() > previous.Severity.Rank():
+	case known && now.Sub(previous.Sent) < m.options.cooldown:
+		return admission{}, false
+	}
+	a := admission{
+		event: e,
+		state: keyState{
+			Severity: e.Severity,
+			Resolved: e.Resolved,
+			Sent:     now,
+		},
+		previous: previous,
+		known:    known,
+	}
+	m.states[e.Key] = a.state
+	return a, true
+}
+
+// enqueue keeps a single pending event per key. An event undoing the pending
+// one, such as a resolution of an alert fired within the same group, drops
+// both since the sinks have nothing new to learn.
+func (m *Manager) enqueue(a admission) {
+	for i, p := range m.pending {
+		if p.event.Key != a.event.Key {
+			continue
+		}
+		a.previous, a.known = p.previous, p.known
+		if a.undoes() {
+			m.restore(a)
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			return
+		}
+		m.pending[i] = a
+		return
+	}
+	m.pending = append(m.pending, a)
+}
+
+// undoes reports whether the event brings its key back to the previous state.
+func (a admission) undoes() bool {
+	if !a.known {
+		return a.event.Resolved
+	}
+	if a.previous.Resolved != a.event.Resolved {
+		return false
+	}
+	return a.event.Resolved || a.previous.Severity == a.event.Severity
+}
+
+// restore must be called with mtx held.
+func (m *Manager) restore(a admission) {
+	if a.known {
+		m.states[a.event.Key] = a.previous
+		return
+	}
+	delete(m.states, a.event.Key)
+}
+
+func (m *Manager) AddSilence(s Silence) (string, error) {
+	if s.Name == "" && s.Currency == "" && s.Key == "" && len(s.Labels) == 0 {
+		return "", fmt.Errorf("%w: at least one matcher is required", ErrInvalidSilence)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if !s.Expires.After(m.now()) {
+		return "", fmt.Errorf("%w: expiry %v is in the past", ErrInvalidSilence, s.Expires)
+	}
+	if s.ID == "" {
+		s.ID = uuid.NewString()
+	}
+	m.silences[s.ID] = s
+	if err := m.save(); err != nil {
+		delete(m.silences, s.ID)
+		return "", err
+	}
+	return s.ID, nil
+}
+
+func (m *Manager) RemoveSilence(id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.silences[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrSilenceNotFound, id)
+	}
+	delete(m.silences, id)
+	return m.save()
+}
+
+func (m *Manager) Silences() []Silence {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.pruneSilences(m.now())
+	result := make([]Silence, 0, len(m.silences))
+	for _, s := range m.silences {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Expires.Before(result[j].Expires)
+	})
+	return result
+}
+
+func (m *Manager) pruneSilences(now time.Time) {
+	for id, s := range m.silences {
+		if !s.Expires.After(now) {
+			delete(m.silences, id)
+		}
+	}
+}
+
+func (m *Manager) load() error {
+	if m.options.store == nil {
+		return nil
+	}
+	var persisted managerState
+	err := m.options.store.Load(managerStateKey, &persisted)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for key, s := range persisted.Keys {
+		m.states[key] = s
+		m.delivered[key] = s
+	}
+	for _, s := range persisted.Silences {
+		m.silences[s.ID] = s
+	}
+	return nil
+}
+
+// save must be called with mtx held.
+func (m *Manager) save() error {
+	if m.options.store == nil {
+		return nil
+	}
+	persisted := managerState{
+		Keys:     m.delivered,
+		Silences: make([]Silence, 0, len(m.silences)),
+	}
+	for _, s := range m.silences {
+		persisted.Silences = append(persisted.Silences, s)
+	}
+	return m.options.store.Save(managerStateKey, persisted)
+}
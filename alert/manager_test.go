@@ -0,0 +1,304 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	mtx     sync.Mutex
+	batches [][]Event
+	// failures is the number of batches rejected before accepting any.
+	failures int
+}
+
+func (s *recordingSink) Notify(ctx context.Context, events []Event) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("delivery failed")
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingSink) received() [][]Event {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([][]Event(nil), s.batches...)
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newManager(next Sink, opts ...ManagerOption) (*Manager, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)}
+	m, _ := NewManager(next, opts...)
+	m.now = clock.Now
+	return m, clock
+}
+
+func newEvent(key string, severity Severity, resolved bool) Event {
+	return Event{
+		Key:      key,
+		Name:     "threshold",
+		Currency: "EUR",
+		Rate:     request.Rate{Date: time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC), Value: 4.75},
+		Severity: severity,
+		Resolved: resolved,
+		Labels:   map[string]string{"band": "above"},
+	}
+}
+
+func TestShouldDropRepeatedAlertWithinCooldown(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, clock := newManager(sink, WithCooldown(time.Hour))
+	event := newEvent("threshold/EUR", SeverityWarning, false)
+
+	// when
+	errFirst := sut.Notify(context.Background(), []Event{event})
+	clock.now = clock.now.Add(30 * time.Minute)
+	errSecond := sut.Notify(context.Background(), []Event{event})
+	clock.now = clock.now.Add(31 * time.Minute)
+	errThird := sut.Notify(context.Background(), []Event{event})
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errThird)
+	assert.Len(t, sink.received(), 2)
+}
+
+func TestShouldBypassCooldownOnEscalationAndResolution(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, _ := newManager(sink, WithCooldown(time.Hour))
+
+	// when
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityInfo, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityCritical, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityCritical, true)})
+
+	// then
+	received := sink.received()
+	assert.Len(t, received, 3)
+	assert.Equal(t, SeverityCritical, received[1][0].Severity)
+	assert.True(t, received[2][0].Resolved)
+}
+
+func TestShouldDropResolutionOfUnknownAlert(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, _ := newManager(sink)
+
+	// when
+	err := sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, true)})
+
+	// then
+	assert.NoError(t, err)
+	assert.Empty(t, sink.received())
+}
+
+func TestShouldGroupAlertsWithinWindow(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, _ := newManager(sink, WithGroupWait(time.Hour))
+
+	// when
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+	sut.Notify(context.Background(), []Event{newEvent("change/EUR/1d", SeverityWarning, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityCritical, false)})
+	assert.Empty(t, sink.received())
+	err := sut.Flush(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	received := sink.received()
+	assert.Len(t, received, 1)
+	assert.Len(t, received[0], 2)
+	assert.Equal(t, "threshold/EUR", received[0][0].Key)
+	assert.Equal(t, SeverityCritical, received[0][0].Severity)
+	assert.Equal(t, "change/EUR/1d", received[0][1].Key)
+}
+
+func TestShouldDropAlertResolvedWithinWindow(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, _ := newManager(sink, WithGroupWait(time.Hour))
+
+	// when
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, true)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, true)})
+	err := sut.Flush(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	assert.Empty(t, sink.received())
+}
+
+func TestShouldSendResolutionOfDeliveredAlertWithinWindow(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, _ := newManager(sink, WithGroupWait(time.Hour))
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+	assert.NoError(t, sut.Flush(context.Background()))
+
+	// when
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityCritical, false)})
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityCritical, true)})
+	err := sut.Flush(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	received := sink.received()
+	assert.Len(t, received, 2)
+	assert.Len(t, received[1], 1)
+	assert.True(t, received[1][0].Resolved)
+}
+
+func TestShouldFlushGroupWhenWindowElapses(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, err := NewManager(sink, WithGroupWait(20*time.Millisecond))
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+
+	// then
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(sink.received()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestShouldMuteSilencedAlertsUntilExpiry(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, clock := newManager(sink, WithCooldown(0))
+	id, err := sut.AddSilence(Silence{
+		Currency: "EUR",
+		Labels:   map[string]string{"band": "above"},
+		Expires:  clock.now.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	event := newEvent("threshold/EUR", SeverityWarning, false)
+
+	// when
+	sut.Notify(context.Background(), []Event{event})
+	silencedCount := len(sink.received())
+	activeSilences := sut.Silences()
+	clock.now = clock.now.Add(time.Hour)
+	sut.Notify(context.Background(), []Event{event})
+
+	// then
+	assert.Zero(t, silencedCount)
+	assert.Len(t, activeSilences, 1)
+	assert.Equal(t, id, activeSilences[0].ID)
+	assert.Empty(t, sut.Silences())
+	assert.Len(t, sink.received(), 1)
+}
+
+func TestShouldRemoveSilence(t *testing.T) {
+	// given
+	sink := &recordingSink{}
+	sut, clock := newManager(sink)
+	id, err := sut.AddSilence(Silence{Name: "threshold", Expires: clock.now.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	// when
+	errRemove := sut.RemoveSilence(id)
+	errMissing := sut.RemoveSilence(id)
+	sut.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+
+	// then
+	assert.NoError(t, errRemove)
+	assert.ErrorIs(t, errMissing, ErrSilenceNotFound)
+	assert.Len(t, sink.received(), 1)
+}
+
+func TestShouldRejectInvalidSilence(t *testing.T) {
+	// given
+	sut, clock := newManager(&recordingSink{})
+
+	// when
+	_, errNoMatchers := sut.AddSilence(Silence{Expires: clock.now.Add(time.Hour)})
+	_, errExpired := sut.AddSilence(Silence{Currency: "EUR", Expires: clock.now})
+
+	// then
+	assert.ErrorIs(t, errNoMatchers, ErrInvalidSilence)
+	assert.ErrorIs(t, errExpired, ErrInvalidSilence)
+}
+
+func TestShouldRestoreStateAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	sink := &recordingSink{}
+	first, clock := newManager(sink, WithManagerStore(store))
+	first.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+	id, err := first.AddSilence(Silence{Currency: "USD", Expires: clock.now.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	// when
+	second, _ := newManager(sink, WithManagerStore(store))
+	second.Notify(context.Background(), []Event{newEvent("threshold/EUR", SeverityWarning, false)})
+
+	// then
+	assert.Len(t, sink.received(), 1)
+	silences := second.Silences()
+	assert.Len(t, silences, 1)
+	assert.Equal(t, id, silences[0].ID)
+}
+
+func TestShouldPersistStateOnlyAfterDelivery(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	sink := &recordingSink{failures: 1}
+	first, _ := newManager(sink, WithManagerStore(store))
+	event := newEvent("threshold/EUR", SeverityWarning, false)
+
+	// when
+	errFailed := first.Notify(context.Background(), []Event{event})
+	second, _ := newManager(sink, WithManagerStore(store))
+	errRetried := second.Notify(context.Background(), []Event{event})
+
+	// then
+	assert.Error(t, errFailed)
+	assert.NoError(t, errRetried)
+	assert.Len(t, sink.received(), 1)
+	var persisted managerState
+	assert.NoError(t, store.Load(managerStateKey, &persisted))
+	assert.Contains(t, persisted.Keys, "threshold/EUR")
+}
+
+func TestShouldLetAlertThroughAgainAfterFailedDelivery(t *testing.T) {
+	// given
+	sink := &recordingSink{failures: 1}
+	sut, _ := newManager(sink, WithCooldown(time.Hour))
+	event := newEvent("threshold/EUR", SeverityWarning, false)
+
+	// when
+	errFailed := sut.Notify(context.Background(), []Event{event})
+	errRetried := sut.Notify(context.Background(), []Event{event})
+
+	// then
+	assert.Error(t, errFailed)
+	assert.NoError(t, errRetried)
+	assert.Len(t, sink.received(), 1)
+}
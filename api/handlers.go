@@ -0,0 +1,505 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	prefix = "/api/v1"
+
+	noCache      = "no-cache"
+	pastMaxAge   = 24 * time.Hour
+	defaultLimit = 100
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type historyRate struct {
+	Date   string  `json:"date"`
+	Value  float64 `json:"value"`
+	Source string  `json:"source"`
+}
+
+type historyResponse struct {
+	Currency string        `json:"currency"`
+	Source   string        `json:"source,omitempty"`
+	From     string        `json:"from,omitempty"`
+	To       string        `json:"to,omitempty"`
+	Rates    []historyRate `json:"rates"`
+}
+
+type targetStatus struct {
+	Target          string    `json:"target"`
+	Ticks           uint64    `json:"ticks"`
+	Successes       uint64    `json:"successes"`
+	Failures        uint64    `json:"failures"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastSuccess     time.Time `json:"last_success"`
+	Paused          bool      `json:"paused"`
+	Interval        string    `json:"interval"`
+	CurrentInterval string    `json:"current_interval"`
+}
+
+type alertEvent struct {
+	Key      string             `json:"key"`
+	Name     string             `json:"name"`
+	Currency string             `json:"currency"`
+	Date     string             `json:"date"`
+	Rate     float64            `json:"rate"`
+	Severity alert.Severity     `json:"severity"`
+	Message  string             `json:"message"`
+	Time     time.Time          `json:"time"`
+	Resolved bool               `json:"resolved"`
+	Labels   map[string]string  `json:"labels,omitempty"`
+	Values   map[string]float64 `json:"values,omitempty"`
+}
+
+type silenceRequest struct {
+	alert.Silence
+	// Duration sets the expiry relative to now when expires is not given.
+	Duration string `json:"duration,omitempty"`
+}
+
+type silenceResponse struct {
+	ID string `json:"id"`
+}
+
+type indicatorValues struct {
+	Currency        string   `json:"currency"`
+	Date            string   `json:"date"`
+	Rate            float64  `json:"rate"`
+	Samples         int      `json:"samples"`
+	SMA             *float64 `json:"sma,omitempty"`
+	EMA             *float64 `json:"ema,omitempty"`
+	BollingerUpper  *float64 `json:"bollinger_upper,omitempty"`
+	BollingerMiddle *float64 `json:"bollinger_middle,omitempty"`
+	BollingerLower  *float64 `json:"bollinger_lower,omitempty"`
+	RSI             *float64 `json:"rsi,omitempty"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, prefix+"/silences") {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/rates", s.handleLatestRates)
+	mux.HandleFunc(prefix+"/rates/", s.handleCurrency)
+	mux.HandleFunc(prefix+"/requests", s.handleRequests)
+	mux.HandleFunc(prefix+"/status", s.handleStatus)
+	mux.HandleFunc(prefix+"/alerts", s.handleAlerts)
+	mux.HandleFunc(prefix+"/indicators", s.handleIndicators)
+	mux.HandleFunc(prefix+"/silences", s.handleSilences)
+	mux.HandleFunc(prefix+"/silences/", s.handleSilence)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	})
+	return mux
+}
+
+func (s *Server) handleLatestRates(w http.ResponseWriter, r *http.Request) {
+	s.mtx.RLock()
+	rates := make([]latestRate, 0, len(s.latest))
+	var modified time.Time
+	for _, rate := range s.latest {
+		rates = append(rates, rate)
+		if rate.UpdatedAt.After(modified) {
+			modified = rate.UpdatedAt
+		}
+	}
+	s.mtx.RUnlock()
+	sortLatest(rates)
+	writeJSON(w, r, rates, s.maxAge(), modified)
+}
+
+func (s *Server) handleCurrency(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix+"/rates/"), "/")
+	currency := strings.ToUpper(parts[0])
+	switch {
+	case currency == "":
+		writeError(w, http.StatusNotFound, "missing currency")
+	case len(parts) == 1:
+		s.handleLatestRate(w, r, currency)
+	case len(parts) == 2 && parts[1] == "history":
+		s.handleHistory(w, r, currency)
+	default:
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+func (s *Server) handleLatestRate(w http.ResponseWriter, r *http.Request, currency string) {
+	s.mtx.RLock()
+	rate, ok := s.latest[currency]
+	s.mtx.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "no rate of %s", currency)
+		return
+	}
+	writeJSON(w, r, rate, s.maxAge(), rate.UpdatedAt)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request, currency string) {
+	if s.options.rates == nil {
+		writeError(w, http.StatusNotImplemented, "rate history is not available")
+		return
+	}
+	query := r.URL.Query()
+	from, errFrom := parseDate(query.Get("from"))
+	to, errTo := parseDate(query.Get("to"))
+	if errFrom != nil || errTo != nil {
+		writeError(w, http.StatusBadRequest, "dates must have the YYYY-MM-DD format")
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to is before from")
+		return
+	}
+	records, err := s.options.rates.Range(r.Context(), store.Query{
+		Currency: currency,
+		Source:   query.Get("source"),
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		slog.Error("api failed to query rate history", "currency", currency, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to query rate history")
+		return
+	}
+
+	resp := historyResponse{
+		Currency: currency,
+		Source:   query.Get("source"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Rates:    make([]historyRate, 0, len(records)),
+	}
+	for _, rec := range records {
+		resp.Rates = append(resp.Rates, historyRate{
+			Date:   rec.Date.Format(time.DateOnly),
+			Value:  rec.Value,
+			Source: rec.Source,
+		})
+	}
+	cacheControl := s.maxAge()
+	if !to.IsZero() && to.Before(today(s.now())) {
+		cacheControl = maxAge(pastMaxAge)
+	}
+	writeJSON(w, r, resp, cacheControl, time.Time{})
+}
+
+func (s *Server) handleRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, ok := parseLimit(w, query.Get("limit"))
+	if !ok {
+		return
+	}
+	var filter func(request.Descriptor) bool
+	switch query.Get("failed") {
+	case "":
+	case "true":
+		filter = request.Descriptor.Failed
+	case "false":
+		filter = func(d request.Descriptor) bool { return !d.Failed() }
+	default:
+		writeError(w, http.StatusBadRequest, "failed must be true or false")
+		return
+	}
+
+	s.mtx.RLock()
+	descs := s.descriptors.newest()
+	s.mtx.RUnlock()
+	result := make([]request.Descriptor, 0, min(limit, len(descs)))
+	for _, d := range descs {
+		if len(result) == limit {
+			break
+		}
+		if filter == nil || filter(d) {
+			result = append(result, d)
+		}
+	}
+	writeJSON(w, r, result, noCache, time.Time{})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.options.monitors == nil {
+		writeError(w, http.StatusNotImplemented, "monitor status is not available")
+		return
+	}
+	statuses := []targetStatus{}
+	for _, target := range s.options.monitors.Targets() {
+		control, err := s.options.monitors.Control(target)
+		if err != nil {
+			continue
+		}
+		stats := control.Stats()
+		status := targetStatus{
+			Target:          target,
+			Ticks:           stats.Ticks,
+			Successes:       stats.Successes,
+			Failures:        stats.Failures,
+			LastSuccess:     stats.LastSuccess,
+			Paused:          stats.Paused,
+			Interval:        stats.Interval.String(),
+			CurrentInterval: stats.CurrentInterval.String(),
+		}
+		if stats.LastError != nil {
+			status.LastError = stats.LastError.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, r, statuses, noCache, time.Time{})
+}
+
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, ok := parseLimit(w, query.Get("limit"))
+	if !ok {
+		return
+	}
+	currency := strings.ToUpper(query.Get("currency"))
+
+	s.mtx.RLock()
+	events := s.alerts.newest()
+	s.mtx.RUnlock()
+	result := make([]alertEvent, 0, min(limit, len(events)))
+	for _, e := range events {
+		if len(result) == limit {
+			break
+		}
+		if currency != "" && e.Currency != currency {
+			continue
+		}
+		result = append(result, alertEvent{
+			Key:      e.Key,
+			Name:     e.Name,
+			Currency: e.Currency,
+			Date:     e.Rate.Date.Format(time.DateOnly),
+			Rate:     e.Rate.Value,
+			Severity: e.Severity,
+			Message:  e.Message,
+			Time:     e.Time,
+			Resolved: e.Resolved,
+			Labels:   e.Labels,
+			Values:   e.Values,
+		})
+	}
+	writeJSON(w, r, result, noCache, time.Time{})
+}
+
+func (s *Server) handleIndicators(w http.ResponseWriter, r *http.Request) {
+	if s.indicators == nil {
+		writeError(w, http.StatusNotImplemented, "indicators are not available")
+		return
+	}
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	result := []indicatorValues{}
+	for _, c := range s.indicators.Currencies() {
+		if currency != "" && c != currency {
+			continue
+		}
+		v, ok := s.indicators.Latest(c)
+		if !ok {
+			continue
+		}
+		values := indicatorValues{
+			Currency: c,
+			Date:     v.Date.Format(time.DateOnly),
+			Rate:     v.Rate,
+			Samples:  v.Samples,
+		}
+		if v.SMAReady {
+			values.SMA = &v.SMA
+		}
+		if v.EMAReady {
+			values.EMA = &v.EMA
+		}
+		if v.BollingerReady {
+			values.BollingerUpper = &v.BollingerUpper
+			values.BollingerMiddle = &v.BollingerMiddle
+			values.BollingerLower = &v.BollingerLower
+		}
+		if v.RSIReady {
+			values.RSI = &v.RSI
+		}
+		result = append(result, values)
+	}
+	writeJSON(w, r, result, noCache, time.Time{})
+}
+
+func (s *Server) handleSilences(w http.ResponseWriter, r *http.Request) {
+	if s.options.silences == nil {
+		writeError(w, http.StatusNotImplemented, "silences are not available")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, r, s.options.silences.Silences(), noCache, time.Time{})
+	case http.MethodPost:
+		s.addSilence(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Server) addSilence(w http.ResponseWriter, r *http.Request) {
+	var req silenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid silence: %v", err)
+		return
+	}
+	if req.Expires.IsZero() && req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid duration: %v", err)
+			return
+		}
+		req.Expires = s.now().Add(duration)
+	}
+	id, err := s.options.silences.AddSilence(req.Silence)
+	if errors.Is(err, alert.ErrInvalidSilence) {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err != nil {
+		slog.Error("api failed to add silence", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to add silence")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Location", prefix+"/silences/"+id)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(silenceResponse{ID: id})
+}
+
+func (s *Server) handleSilence(w http.ResponseWriter, r *http.Request) {
+	if s.options.silences == nil {
+		writeError(w, http.StatusNotImplemented, "silences are not available")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, prefix+"/silences/")
+	err := s.options.silences.RemoveSilence(id)
+	if errors.Is(err, alert.ErrSilenceNotFound) {
+		writeError(w, http.StatusNotFound, "no silence %s", id)
+		return
+	}
+	if err != nil {
+		slog.Error("api failed to remove silence", "id", id, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to remove silence")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) maxAge() string {
+	return maxAge(s.options.maxAge)
+}
+
+func maxAge(d time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d", int(d.Seconds()))
+}
+
+// writeJSON answers with v unless the client already holds the same
+// representation, as told by its If-None-Match header.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any, cacheControl string, modified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("api failed to encode response", "path", r.URL.Path, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)+1))
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+}
+
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func parseLimit(w http.ResponseWriter, value string) (int, bool) {
+	if value == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive number")
+		return 0, false
+	}
+	return limit, true
+}
+
+func sortLatest(rates []latestRate) {
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+}
+
+func today(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/monitor"
+	"github.com/koenno/currency-price-monitor/processor/indicators"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+)
+
+const (
+	defaultHistorySize = 500
+	defaultMaxAge      = time.Minute
+)
+
+// Monitors gives access to the status of monitored targets, as
+// monitor.MultiMonitor does.
+type Monitors interface {
+	Targets() []string
+	Control(name string) (*monitor.Control, error)
+}
+
+// Silences manages silences of alerts, as alert.Manager does.
+type Silences interface {
+	AddSilence(s alert.Silence) (string, error)
+	RemoveSilence(id string) error
+	Silences() []alert.Silence
+}
+
+type options struct {
+	rates            store.RateStore
+	monitors         Monitors
+	silences         Silences
+	historySize      int
+	alertHistorySize int
+	maxAge           time.Duration
+	indicators       *indicators.Config
+}
+
+type Option func(*options)
+
+// WithRates serves historical range queries from the rate store.
+func WithRates(rates store.RateStore) Option {
+	return func(o *options) {
+		o.rates = rates
+	}
+}
+
+func WithMonitors(monitors Monitors) Option {
+	return func(o *options) {
+		o.monitors = monitors
+	}
+}
+
+// WithSilences lets clients list, add and remove silences of alerts.
+func WithSilences(silences Silences) Option {
+	return func(o *options) {
+		o.silences = silences
+	}
+}
+
+// WithHistorySize sets how many recent descriptors and alerts are kept.
+func WithHistorySize(descriptors, alerts int) Option {
+	return func(o *options) {
+		o.historySize = descriptors
+		o.alertHistorySize = alerts
+	}
+}
+
+// WithIndicators computes technical indicators of the incoming rates and
+// serves their latest values.
+func WithIndicators(cfg indicators.Config) Option {
+	return func(o *options) {
+		o.indicators = &cfg
+	}
+}
+
+// WithMaxAge sets how long clients may cache the latest rates.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = maxAge
+	}
+}
+
+type latestRate struct {
+	Currency  string    `json:"currency"`
+	Source    string    `json:"source"`
+	Date      string    `json:"date"`
+	Value     float64   `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	date time.Time
+}
+
+// Server is a scheduler processor keeping the latest rates, recent
+// descriptors and alerts, and serving them together with the rate history
+// over HTTP.
+type Server struct {
+	options options
+	now     func() time.Time
+	mux     *http.ServeMux
+	// indicators is nil unless enabled with WithIndicators.
+	indicators *indicators.Processor
+
+	mtx         sync.RWMutex
+	latest      map[string]latestRate
+	descriptors *ring[request.Descriptor]
+	alerts      *ring[alert.Event]
+}
+
+func NewServer(opts ...Option) *Server {
+	cfg := options{
+		historySize:      defaultHistorySize,
+		alertHistorySize: defaultHistorySize,
+		maxAge:           defaultMaxAge,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	s := &Server{
+		options:     cfg,
+		now:         time.Now,
+		latest:      make(map[string]latestRate),
+		descriptors: newRing[request.Descriptor](cfg.historySize),
+		alerts:      newRing[alert.Event](cfg.alertHistorySize),
+	}
+	if cfg.indicators != nil {
+		s.indicators = indicators.New(*cfg.indicators)
+	}
+	s.mux = s.routes()
+	return s
+}
+
+func (s *Server) Process(ctx context.Context, desc request.Descriptor) error {
+	if s.indicators != nil {
+		if err := s.indicators.Process(ctx, desc); err != nil {
+			return err
+		}
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.descriptors.add(desc)
+	if len(desc.Payload.Rates) == 0 {
+		return nil
+	}
+	latest := desc.Payload.Rates[0]
+	for _, r := range desc.Payload.Rates[1:] {
+		if r.Date.After(latest.Date) {
+			latest = r
+		}
+	}
+	if current, ok := s.latest[desc.Payload.Name]; ok {
+		// polls repeating the same rate keep UpdatedAt, and with it Last-Modified
+		unchanged := current.date.Equal(latest.Date) && current.Value == latest.Value
+		if unchanged || current.date.After(latest.Date) {
+			return nil
+		}
+	}
+	s.latest[desc.Payload.Name] = latestRate{
+		Currency:  desc.Payload.Name,
+		Source:    desc.Target,
+		Date:      latest.Date.Format(time.DateOnly),
+		Value:     latest.Value,
+		UpdatedAt: s.now().UTC(),
+		date:      latest.Date,
+	}
+	return nil
+}
+
+// AlertSink records alerts before passing them to next.
+func (s *Server) AlertSink(next alert.Sink) alert.Sink {
+	return alertRecorder{
+		next:   next,
+		server: s,
+	}
+}
+
+type alertRecorder struct {
+	next   alert.Sink
+	server *Server
+}
+
+func (r alertRecorder) Notify(ctx context.Context, events []alert.Event) error {
+	r.server.mtx.Lock()
+	for _, e := range events {
+		r.server.alerts.add(e)
+	}
+	r.server.mtx.Unlock()
+	return r.next.Notify(ctx, events)
+}
+
+// ring keeps the most recent items up to its capacity.
+type ring[T any] struct {
+	items []T
+	next  int
+	full  bool
+}
+
+func newRing[T any](capacity int) *ring[T] {
+	return &ring[T]{
+		items: make([]T, capacity),
+	}
+}
+
+func (r *ring[T]) add(item T) {
+	if len(r.items) == 0 {
+		return
+	}
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// newest returns items from the most recent one.
+func (r *ring[T]) newest() []T {
+	size := r.next
+	if r.full {
+		size = len(r.items)
+	}
+	items := make([]T, 0, size)
+	for i := 1; i <= size; i++ {
+		items = append(items, r.items[(r.next-i+len(r.items))%len(r.items)])
+	}
+	return items
+}
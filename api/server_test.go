@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	alertmocks "github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/monitor"
+	monitormocks "github.com/koenno/currency-price-monitor/monitor/mocks"
+	"github.com/koenno/currency-price-monitor/processor/indicators"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+	storemocks "github.com/koenno/currency-price-monitor/store/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func newRatesDescriptor(target, currency string, rates ...request.Rate) request.Descriptor {
+	return request.Descriptor{
+		ID:     currency + "-" + target,
+		Target: target,
+		Payload: request.Currency{
+			Name:  currency,
+			Rates: rates,
+		},
+	}
+}
+
+func get(t *testing.T, sut http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	sut.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldServeLatestRateOfEachCurrency(t *testing.T) {
+	// given
+	sut := NewServer(WithMaxAge(30 * time.Second))
+	sut.now = func() time.Time { return time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC) }
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR",
+		request.Rate{Date: date("2023-10-03"), Value: 4.61},
+		request.Rate{Date: date("2023-10-02"), Value: 4.6}))
+	sut.Process(context.Background(), newRatesDescriptor("backfill", "EUR",
+		request.Rate{Date: date("2023-09-01"), Value: 4.4}))
+	sut.Process(context.Background(), newRatesDescriptor("nbp-usd", "USD",
+		request.Rate{Date: date("2023-10-03"), Value: 4.35}))
+
+	// when
+	all := get(t, sut, "/api/v1/rates")
+	eur := get(t, sut, "/api/v1/rates/eur")
+	unknown := get(t, sut, "/api/v1/rates/chf")
+
+	// then
+	assert.Equal(t, http.StatusOK, all.Code)
+	assert.JSONEq(t, `[
+		{"currency": "EUR", "source": "nbp-eur", "date": "2023-10-03", "value": 4.61, "updated_at": "2023-10-03T12:00:00Z"},
+		{"currency": "USD", "source": "nbp-usd", "date": "2023-10-03", "value": 4.35, "updated_at": "2023-10-03T12:00:00Z"}
+	]`, all.Body.String())
+	assert.Equal(t, "public, max-age=30", all.Header().Get("Cache-Control"))
+	assert.Equal(t, "Tue, 03 Oct 2023 12:00:00 GMT", all.Header().Get("Last-Modified"))
+	assert.Equal(t, http.StatusOK, eur.Code)
+	assert.JSONEq(t, `{"currency": "EUR", "source": "nbp-eur", "date": "2023-10-03", "value": 4.61, "updated_at": "2023-10-03T12:00:00Z"}`,
+		eur.Body.String())
+	assert.Equal(t, http.StatusNotFound, unknown.Code)
+}
+
+func TestShouldKeepUpdateTimeWhileRateIsUnchanged(t *testing.T) {
+	// given
+	sut := NewServer()
+	now := time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)
+	sut.now = func() time.Time { return now }
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR", request.Rate{Date: date("2023-10-03"), Value: 4.61}))
+	first := get(t, sut, "/api/v1/rates/EUR")
+
+	// when
+	now = now.Add(time.Minute)
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR", request.Rate{Date: date("2023-10-03"), Value: 4.61}))
+	repeated := get(t, sut, "/api/v1/rates/EUR", "If-None-Match", first.Header().Get("ETag"))
+	now = now.Add(time.Minute)
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR", request.Rate{Date: date("2023-10-03"), Value: 4.62}))
+	corrected := get(t, sut, "/api/v1/rates/EUR")
+
+	// then
+	assert.Equal(t, http.StatusNotModified, repeated.Code)
+	assert.Equal(t, "Tue, 03 Oct 2023 12:00:00 GMT", repeated.Header().Get("Last-Modified"))
+	assert.Equal(t, http.StatusOK, corrected.Code)
+	assert.Equal(t, "Tue, 03 Oct 2023 12:02:00 GMT", corrected.Header().Get("Last-Modified"))
+}
+
+func TestShouldAnswerNotModifiedForMatchingETag(t *testing.T) {
+	// given
+	sut := NewServer()
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR", request.Rate{Date: date("2023-10-03"), Value: 4.61}))
+	first := get(t, sut, "/api/v1/rates/EUR")
+
+	// when
+	second := get(t, sut, "/api/v1/rates/EUR", "If-None-Match", first.Header().Get("ETag"))
+	sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR", request.Rate{Date: date("2023-10-04"), Value: 4.62}))
+	third := get(t, sut, "/api/v1/rates/EUR", "If-None-Match", first.Header().Get("ETag"))
+
+	// then
+	assert.NotEmpty(t, first.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, second.Code)
+	assert.Empty(t, second.Body.String())
+	assert.Equal(t, http.StatusOK, third.Code)
+}
+
+func TestShouldServeRateHistoryFromStore(t *testing.T) {
+	// given
+	ratesMock := storemocks.NewRateStore(t)
+	sut := NewServer(WithRates(ratesMock))
+	sut.now = func() time.Time { return time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC) }
+
+	ratesMock.EXPECT().Range(mock.Anything, store.Query{
+		Currency: "EUR",
+		From:     date("2023-10-02"),
+		To:       date("2023-10-03"),
+	}).Return([]store.Record{
+		{Currency: "EUR", Source: "nbp-eur", Date: date("2023-10-02"), Value: 4.6},
+		{Currency: "EUR", Source: "nbp-eur", Date: date("2023-10-03"), Value: 4.61},
+	}, nil).Once()
+
+	// when
+	actual := get(t, sut, "/api/v1/rates/eur/history?from=2023-10-02&to=2023-10-03")
+
+	// then
+	assert.Equal(t, http.StatusOK, actual.Code)
+	assert.JSONEq(t, `{"currency": "EUR", "from": "2023-10-02", "to": "2023-10-03", "rates": [
+		{"date": "2023-10-02", "value": 4.6, "source": "nbp-eur"},
+		{"date": "2023-10-03", "value": 4.61, "source": "nbp-eur"}
+	]}`, actual.Body.String())
+	assert.Equal(t, "public, max-age=86400", actual.Header().Get("Cache-Control"))
+}
+
+func TestShouldRejectInvalidHistoryQueries(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+	}{
+		{name: "malformed date", target: "/api/v1/rates/EUR/history?from=03.10.2023", expected: http.StatusBadRequest},
+		{name: "reversed range", target: "/api/v1/rates/EUR/history?from=2023-10-03&to=2023-10-02", expected: http.StatusBadRequest},
+		{name: "unknown path", target: "/api/v1/rates/EUR/future", expected: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			sut := NewServer(WithRates(storemocks.NewRateStore(t)))
+
+			// when
+			actual := get(t, sut, tt.target)
+
+			// then
+			assert.Equal(t, tt.expected, actual.Code)
+			assert.Contains(t, actual.Body.String(), `"error"`)
+		})
+	}
+}
+
+func TestShouldServeNewestRequestsFirst(t *testing.T) {
+	// given
+	sut := NewServer(WithHistorySize(2, 2))
+	sut.Process(context.Background(), request.Descriptor{ID: "1"})
+	sut.Process(context.Background(), request.Descriptor{ID: "2", ErrorKind: request.ErrorTransport})
+	sut.Process(context.Background(), request.Descriptor{ID: "3"})
+
+	// when
+	all := get(t, sut, "/api/v1/requests")
+	failed := get(t, sut, "/api/v1/requests?failed=true")
+	limited := get(t, sut, "/api/v1/requests?limit=1")
+
+	// then
+	assert.Equal(t, []string{"3", "2"}, descriptorIDs(t, all))
+	assert.Equal(t, []string{"2"}, descriptorIDs(t, failed))
+	assert.Equal(t, []string{"3"}, descriptorIDs(t, limited))
+	assert.Equal(t, "no-cache", all.Header().Get("Cache-Control"))
+}
+
+func descriptorIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var descs []request.Descriptor
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &descs))
+	ids := []string{}
+	for _, d := range descs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func TestShouldServeAlertHistoryAndPassAlertsOn(t *testing.T) {
+	// given
+	sinkMock := alertmocks.NewSink(t)
+	sut := NewServer()
+	events := []alert.Event{
+		{Key: "threshold/EUR", Name: "threshold", Currency: "EUR", Severity: alert.SeverityWarning,
+			Rate: request.Rate{Date: date("2023-10-03"), Value: 4.71}, Message: "above band"},
+		{Key: "change/USD", Name: "change", Currency: "USD", Severity: alert.SeverityInfo},
+	}
+
+	sinkMock.EXPECT().Notify(mock.Anything, events).Return(nil).Once()
+
+	// when
+	err := sut.AlertSink(sinkMock).Notify(context.Background(), events)
+	actual := get(t, sut, "/api/v1/alerts?currency=eur")
+
+	// then
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "threshold/EUR", "name": "threshold", "currency": "EUR", "date": "2023-10-03",
+		"rate": 4.71, "severity": "warning", "message": "above band", "time": "0001-01-01T00:00:00Z", "resolved": false}]`,
+		actual.Body.String())
+}
+
+func TestShouldServeMonitorStatus(t *testing.T) {
+	// given
+	requesterMock := monitormocks.NewRequester(t)
+	monitors := monitor.NewMulti(1)
+	req, _ := http.NewRequest(http.MethodGet, "http://some.domain.com", nil)
+	assert.NoError(t, monitors.Add(monitor.Target{
+		Name:           "nbp-eur",
+		Requester:      requesterMock,
+		Request:        req,
+		RequestsNumber: 1,
+		Interval:       time.Hour,
+	}))
+	sut := NewServer(WithMonitors(monitors))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(request.Descriptor{}, errors.New("failure")).Maybe()
+
+	// when
+	<-monitors.Start(ctx)
+	actual := get(t, sut, "/api/v1/status")
+
+	// then
+	var statuses []map[string]any
+	assert.NoError(t, json.Unmarshal(actual.Body.Bytes(), &statuses))
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "nbp-eur", statuses[0]["target"])
+	assert.Equal(t, "1h0m0s", statuses[0]["interval"])
+}
+
+func TestShouldServeLatestIndicatorsOfEachCurrency(t *testing.T) {
+	// given
+	sut := NewServer(WithIndicators(indicators.Config{
+		SMAPeriod:       2,
+		EMAPeriod:       3,
+		BollingerPeriod: 3,
+		BollingerK:      2,
+		RSIPeriod:       2,
+	}))
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("nbp-eur", "EUR",
+		request.Rate{Date: date("2023-10-02"), Value: 4.5},
+		request.Rate{Date: date("2023-10-03"), Value: 4.7},
+	)))
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("nbp-usd", "USD",
+		request.Rate{Date: date("2023-10-03"), Value: 4.2},
+	)))
+
+	// when
+	actual := get(t, sut, "/api/v1/indicators")
+	filtered := get(t, sut, "/api/v1/indicators?currency=usd")
+
+	// then
+	assert.Equal(t, http.StatusOK, actual.Code)
+	assert.JSONEq(t, `[
+		{"currency": "EUR", "date": "2023-10-03", "rate": 4.7, "samples": 2, "sma": 4.6},
+		{"currency": "USD", "date": "2023-10-03", "rate": 4.2, "samples": 1}]`,
+		actual.Body.String())
+	assert.JSONEq(t, `[{"currency": "USD", "date": "2023-10-03", "rate": 4.2, "samples": 1}]`,
+		filtered.Body.String())
+}
+
+func TestShouldRejectIndicatorsWhenDisabled(t *testing.T) {
+	// given
+	sut := NewServer()
+
+	// when
+	actual := get(t, sut, "/api/v1/indicators")
+
+	// then
+	assert.Equal(t, http.StatusNotImplemented, actual.Code)
+}
+
+func TestShouldManageSilences(t *testing.T) {
+	// given
+	manager, err := alert.NewManager(alertmocks.NewSink(t))
+	assert.NoError(t, err)
+	sut := NewServer(WithSilences(manager))
+	body := strings.NewReader(`{"currency": "EUR", "name": "threshold", "comment": "holiday", "duration": "1h"}`)
+
+	// when
+	created := httptest.NewRecorder()
+	sut.ServeHTTP(created, httptest.NewRequest(http.MethodPost, "/api/v1/silences", body))
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(created.Body.Bytes(), &resp))
+	listed := get(t, sut, "/api/v1/silences")
+	removed := httptest.NewRecorder()
+	sut.ServeHTTP(removed, httptest.NewRequest(http.MethodDelete, "/api/v1/silences/"+resp["id"], nil))
+	missing := httptest.NewRecorder()
+	sut.ServeHTTP(missing, httptest.NewRequest(http.MethodDelete, "/api/v1/silences/"+resp["id"], nil))
+
+	// then
+	assert.Equal(t, http.StatusCreated, created.Code)
+	assert.Equal(t, "/api/v1/silences/"+resp["id"], created.Header().Get("Location"))
+	var silences []alert.Silence
+	assert.NoError(t, json.Unmarshal(listed.Body.Bytes(), &silences))
+	assert.Len(t, silences, 1)
+	assert.Equal(t, resp["id"], silences[0].ID)
+	assert.Equal(t, "EUR", silences[0].Currency)
+	assert.Equal(t, http.StatusNoContent, removed.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Empty(t, manager.Silences())
+}
+
+func TestShouldRejectInvalidSilence(t *testing.T) {
+	// given
+	manager, err := alert.NewManager(alertmocks.NewSink(t))
+	assert.NoError(t, err)
+	sut := NewServer(WithSilences(manager))
+
+	// when
+	noMatchers := httptest.NewRecorder()
+	sut.ServeHTTP(noMatchers, httptest.NewRequest(http.MethodPost, "/api/v1/silences",
+		strings.NewReader(`{"duration": "1h"}`)))
+	badDuration := httptest.NewRecorder()
+	sut.ServeHTTP(badDuration, httptest.NewRequest(http.MethodPost, "/api/v1/silences",
+		strings.NewReader(`{"currency": "EUR", "duration": "soon"}`)))
+	disabled := get(t, NewServer(), "/api/v1/silences")
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, noMatchers.Code)
+	assert.Equal(t, http.StatusBadRequest, badDuration.Code)
+	assert.Equal(t, http.StatusNotImplemented, disabled.Code)
+}
+
+func TestShouldRejectUnsupportedMethods(t *testing.T) {
+	// given
+	sut := NewServer()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/rates", nil)
+	rec := httptest.NewRecorder()
+
+	// when
+	sut.ServeHTTP(rec, req)
+
+	// then
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
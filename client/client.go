@@ -40,41 +40,50 @@ func New[T any](converter Converter[T]) Client[T] {
 
 func (c Client[T]) Process(req *http.Request) (request.Descriptor, error) {
 	desc := request.Descriptor{
-		ID:   uuid.NewString(),
-		URL:  req.URL.String(),
-		Time: time.Now(),
+		ID:       uuid.NewString(),
+		URL:      req.URL.String(),
+		Time:     time.Now(),
+		Attempts: 1,
 	}
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		desc.ErrorKind = request.ErrorTransport
 		return desc, fmt.Errorf("%w: %v", ErrSendRequest, err)
 	}
 	desc.Duration = time.Since(desc.Time)
+	desc.StatusCode = resp.StatusCode
 
 	defer resp.Body.Close()
 	payloadBytes, err := io.ReadAll(resp.Body)
+	desc.ResponseSize = int64(len(payloadBytes))
 	if err != nil {
+		desc.ErrorKind = request.ErrorTransport
 		return desc, fmt.Errorf("%w: unable to read body: %v", ErrResponse, err)
 	}
 
 	desc.ValidStatusCode = resp.StatusCode == http.StatusOK
 	if resp.StatusCode != http.StatusOK {
+		desc.ErrorKind = request.ErrorHTTPStatus
 		return desc, fmt.Errorf("%w: status code %d; body %s", ErrResponse, resp.StatusCode, string(payloadBytes))
 	}
 
 	desc.JSON = strings.Contains(resp.Header.Get("content-type"), "application/json")
 	if !desc.JSON {
+		desc.ErrorKind = request.ErrorPayload
 		return desc, fmt.Errorf("%w: unsupported %s", ErrResponsePayload, resp.Header.Get("content-type"))
 	}
 
 	desc.Valid = json.Valid(payloadBytes)
 	if !desc.Valid {
+		desc.ErrorKind = request.ErrorPayload
 		return desc, fmt.Errorf("%w: invalid json", ErrResponsePayload)
 	}
 
 	var payload T
 	err = json.Unmarshal(payloadBytes, &payload)
 	if err != nil {
+		desc.ErrorKind = request.ErrorDecode
 		return desc, fmt.Errorf("%w: unable to decode body to json %v", ErrResponse, err)
 	}
 
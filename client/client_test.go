@@ -33,6 +33,8 @@ func TestShouldReturnErrorWhenResponseStatusCodeIsNotSuccessful(t *testing.T) {
 	assert.False(t, desc.JSON)
 	assert.False(t, desc.Valid)
 	assert.NotZero(t, desc.Duration)
+	assert.Equal(t, http.StatusNotFound, desc.StatusCode)
+	assert.Equal(t, request.ErrorHTTPStatus, desc.ErrorKind)
 	assert.Zero(t, desc.Payload)
 }
 
@@ -57,6 +59,8 @@ func TestShouldReturnErrorWhenContentTypeIsNotJSON(t *testing.T) {
 	assert.False(t, desc.JSON)
 	assert.False(t, desc.Valid)
 	assert.NotZero(t, desc.Duration)
+	assert.Equal(t, http.StatusOK, desc.StatusCode)
+	assert.Equal(t, request.ErrorPayload, desc.ErrorKind)
 	assert.Zero(t, desc.Payload)
 }
 
@@ -82,6 +86,8 @@ func TestShouldReturnErrorWhenJSONIsInvalid(t *testing.T) {
 	assert.True(t, desc.JSON)
 	assert.False(t, desc.Valid)
 	assert.NotZero(t, desc.Duration)
+	assert.Equal(t, int64(len(`{ "invalidJson": true `)), desc.ResponseSize)
+	assert.Equal(t, request.ErrorPayload, desc.ErrorKind)
 	assert.Zero(t, desc.Payload)
 }
 
@@ -126,9 +132,52 @@ func TestShouldFillAllDescriptorDataWhenNoError(t *testing.T) {
 	assert.True(t, desc.JSON)
 	assert.True(t, desc.Valid)
 	assert.NotZero(t, desc.Duration)
+	assert.Equal(t, http.StatusOK, desc.StatusCode)
+	assert.NotZero(t, desc.ResponseSize)
+	assert.Equal(t, 1, desc.Attempts)
+	assert.Zero(t, desc.ErrorKind)
+	assert.False(t, desc.Failed())
 	assert.Equal(t, expectedCurrency, desc.Payload)
 }
 
+func TestShouldClassifyTransportError(t *testing.T) {
+	// given
+	converterMock := mocks.NewConverter[string](t)
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	fakeServer.Close()
+	req, _ := http.NewRequest(http.MethodGet, fakeServer.URL, nil)
+	sut := New[string](converterMock)
+
+	// when
+	desc, err := sut.Process(req)
+
+	// then
+	assert.ErrorIs(t, err, ErrSendRequest)
+	assert.Equal(t, request.ErrorTransport, desc.ErrorKind)
+	assert.Equal(t, 1, desc.Attempts)
+	assert.Zero(t, desc.StatusCode)
+	assert.True(t, desc.Failed())
+}
+
+func TestShouldClassifyDecodeError(t *testing.T) {
+	// given
+	converterMock := mocks.NewConverter[[]int](t)
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"rates": []}`))
+	}))
+	req, _ := http.NewRequest(http.MethodGet, fakeServer.URL, nil)
+	sut := New[[]int](converterMock)
+
+	// when
+	desc, err := sut.Process(req)
+
+	// then
+	assert.ErrorIs(t, err, ErrResponse)
+	assert.Equal(t, request.ErrorDecode, desc.ErrorKind)
+	assert.True(t, desc.Valid)
+}
+
 func newDate(date string) time.Time {
 	t, _ := time.Parse(time.DateOnly, date)
 	return t
@@ -2,10 +2,12 @@ package nbp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"golang.org/x/text/currency"
 )
@@ -24,12 +26,21 @@ type Format string
 
 const (
 	FormatJSON Format = "json"
+
+	// NBP rejects date range queries longer than 93 days.
+	MaxRangeDays = 93
+)
+
+var (
+	ErrInvalidRange = errors.New("invalid date range")
 )
 
 type options struct {
 	historyInDays uint
 	currencyUnit  currency.Unit
 	format        Format
+	from          time.Time
+	to            time.Time
 }
 
 func defaultOptions() *options {
@@ -54,6 +65,13 @@ func WithCurrency(unit currency.Unit) RequestOption {
 	}
 }
 
+func WithDateRange(from, to time.Time) RequestOption {
+	return func(o *options) {
+		o.from = from
+		o.to = to
+	}
+}
+
 func WithFormat(format Format) RequestOption {
 	return func(o *options) {
 		o.format = format
@@ -61,6 +79,7 @@ func WithFormat(format Format) RequestOption {
 }
 
 // http://api.nbp.pl/api/exchangerates/rates/a/eur/last/100/?format=json
+// http://api.nbp.pl/api/exchangerates/rates/a/eur/2023-09-01/2023-09-30/?format=json
 func (c CurrencyClient) NewRequest(ctx context.Context, opts ...RequestOption) (*http.Request, error) {
 	cfg := defaultOptions()
 	for _, o := range opts {
@@ -68,8 +87,16 @@ func (c CurrencyClient) NewRequest(ctx context.Context, opts ...RequestOption) (
 	}
 
 	endpoint := "api/exchangerates/rates/a"
-	rawURL := fmt.Sprintf("http://%s/%s/%s/last/%d",
-		c.domain, endpoint, strings.ToLower(cfg.currencyUnit.String()), cfg.historyInDays)
+	code := strings.ToLower(cfg.currencyUnit.String())
+	rawURL := fmt.Sprintf("http://%s/%s/%s/last/%d", c.domain, endpoint, code, cfg.historyInDays)
+	if !cfg.from.IsZero() || !cfg.to.IsZero() {
+		if cfg.to.Before(cfg.from) || cfg.to.Sub(cfg.from) >= MaxRangeDays*24*time.Hour {
+			return nil, fmt.Errorf("%w: %s - %s", ErrInvalidRange,
+				cfg.from.Format(time.DateOnly), cfg.to.Format(time.DateOnly))
+		}
+		rawURL = fmt.Sprintf("http://%s/%s/%s/%s/%s", c.domain, endpoint, code,
+			cfg.from.Format(time.DateOnly), cfg.to.Format(time.DateOnly))
+	}
 	URL, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create url: %v", err)
@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/currency"
 )
 
 func TestShouldReturnProperURLWithDefaultValues(t *testing.T) {
@@ -28,3 +30,31 @@ func TestShouldReturnProperURLWithDefaultValues(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(FormatJSON), query.Get("format"))
 }
+
+func TestShouldReturnDateRangeURL(t *testing.T) {
+	// given
+	domain := "something.com"
+	client := NewCurrencyClient(domain)
+	from := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC)
+
+	// when
+	req, err := client.NewRequest(context.Background(), WithCurrency(currency.USD), WithDateRange(from, to))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/exchangerates/rates/a/usd/2023-09-01/2023-09-30", req.URL.Path)
+}
+
+func TestShouldRejectTooLongDateRange(t *testing.T) {
+	// given
+	client := NewCurrencyClient("something.com")
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, MaxRangeDays)
+
+	// when
+	_, err := client.NewRequest(context.Background(), WithDateRange(from, to))
+
+	// then
+	assert.ErrorIs(t, err, ErrInvalidRange)
+}
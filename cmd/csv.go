@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/csvrate"
+	"github.com/koenno/currency-price-monitor/processor"
+	"github.com/koenno/currency-price-monitor/processor/rules"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/scheduler"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/koenno/currency-price-monitor/store"
+)
+
+const (
+	importTarget = "csv-import"
+)
+
+type csvFormatFlags struct {
+	columns    *string
+	delimiter  *string
+	decimal    *string
+	dateLayout *string
+	header     *bool
+}
+
+// newCSVFormatFlags defines the format flags on fs, each name starting with prefix.
+func newCSVFormatFlags(fs *flag.FlagSet, prefix string) csvFormatFlags {
+	def := csvrate.DefaultFormat()
+	columns := make([]string, len(def.Columns))
+	for i, c := range def.Columns {
+		columns[i] = string(c)
+	}
+	return csvFormatFlags{
+		columns:    fs.String(prefix+"columns", strings.Join(columns, ","), "comma separated columns: date, currency, source, value"),
+		delimiter:  fs.String(prefix+"delimiter", string(def.Delimiter), "field delimiter"),
+		decimal:    fs.String(prefix+"decimal", string(def.DecimalSeparator), "decimal separator of values"),
+		dateLayout: fs.String(prefix+"date-format", def.DateLayout, "Go layout of dates"),
+		header:     fs.Bool(prefix+"header", def.Header, "whether the first line holds column names"),
+	}
+}
+
+func (f csvFormatFlags) format() csvrate.Format {
+	columns, err := csvrate.ParseColumns(*f.columns)
+	if err != nil {
+		log.Fatalf("invalid columns: %v", err)
+	}
+	format := csvrate.Format{
+		Columns:          columns,
+		Delimiter:        singleRune("delimiter", *f.delimiter),
+		DecimalSeparator: singleRune("decimal", *f.decimal),
+		DateLayout:       *f.dateLayout,
+		Header:           *f.header,
+	}
+	if err := format.Validate(); err != nil {
+		log.Fatalf("invalid CSV format: %v", err)
+	}
+	return format
+}
+
+func singleRune(name, value string) rune {
+	if value == `\t` {
+		return '\t'
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) {
+		log.Fatalf("%s must be a single character, got %q", name, value)
+	}
+	return r
+}
+
+func parseDateFlag(name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		log.Fatalf("invalid %s date %q: %v", name, value, err)
+	}
+	return date
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	currencyCode := fs.String("currency", "", "currency code to export")
+	source := fs.String("source", "", "source of rates; all sources when empty")
+	from := fs.String("from", "", "first date (YYYY-MM-DD) of the exported range")
+	to := fs.String("to", "", "last date (YYYY-MM-DD) of the exported range")
+	outPath := fs.String("out", "-", "output file; - writes to stdout")
+	formatFlags := newCSVFormatFlags(fs, "")
+	fs.Parse(args)
+
+	if *currencyCode == "" {
+		log.Fatalf("export requires -currency")
+	}
+	format := formatFlags.format()
+
+	rates, _, err := openRateStore()
+	if err != nil {
+		log.Fatalf("failed to open rate store: %v", err)
+	}
+	defer rates.Close()
+
+	records, err := rates.Range(context.Background(), store.Query{
+		Currency: strings.ToUpper(*currencyCode),
+		Source:   *source,
+		From:     parseDateFlag("from", *from),
+		To:       parseDateFlag("to", *to),
+	})
+	if err != nil {
+		log.Fatalf("failed to query rates: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", *outPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	writer, err := csvrate.NewWriter(out, format)
+	if err != nil {
+		log.Fatalf("invalid CSV format: %v", err)
+	}
+	if err := writer.Write(records); err != nil {
+		log.Fatalf("failed to write CSV: %v", err)
+	}
+}
+
+func runImport(args []string, alertRules []rules.Rule) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	inPath := fs.String("in", "-", "input file; - reads from stdin")
+	currencyCode := fs.String("currency", "", "currency of rows without a currency column")
+	source := fs.String("source", importTarget, "source of rows without a source column")
+	replay := fs.Bool("replay", false, "pass the rates through the processors instead of only storing them")
+	live := fs.Bool("live", false, "with -replay, send alerts to the configured notifiers and keep the processors' state in "+
+		statePath+"; otherwise alerts are only printed and the state is discarded")
+	formatFlags := newCSVFormatFlags(fs, "")
+	fs.Parse(args)
+	format := formatFlags.format()
+
+	var in io.Reader = os.Stdin
+	if *inPath != "-" {
+		f, err := os.Open(*inPath)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", *inPath, err)
+		}
+		defer f.Close()
+		in = f
+	}
+	reader, err := csvrate.NewReader(in, format,
+		csvrate.WithDefaultCurrency(strings.ToUpper(*currencyCode)),
+		csvrate.WithDefaultSource(*source))
+	if err != nil {
+		log.Fatalf("invalid CSV format: %v", err)
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Fatalf("failed to read CSV: %v", err)
+	}
+
+	rates, storageOpts, err := openRateStore()
+	if err != nil {
+		log.Fatalf("failed to open rate store: %v", err)
+	}
+	defer rates.Close()
+
+	if !*replay {
+		if err := rates.Upsert(context.Background(), records); err != nil {
+			log.Fatalf("failed to store rates: %v", err)
+		}
+		log.Printf("imported %d rates", len(records))
+		return
+	}
+
+	var (
+		stateStore state.Store = state.NewMemoryStore()
+		alertSink  alert.Sink  = alert.NewWriterSink(os.Stdout)
+	)
+	if *live {
+		fileStore, err := state.NewFileStore(statePath)
+		if err != nil {
+			log.Fatalf("failed to open state store: %v", err)
+		}
+		alertManager, flushAlerts := newAlertManager(fileStore)
+		defer flushAlerts()
+		stateStore, alertSink = fileStore, alertManager
+	}
+	sched := newScheduler(alertRules, stateStore, alertSink, rates, storageOpts, nil)
+	sched.Register(newWriter(os.Stdout, *stdoutFormat, *stdoutTemplate, *stdoutFilter), scheduler.WithName("stdout"))
+	descs := make(chan request.Descriptor)
+	go func() {
+		defer close(descs)
+		for _, desc := range replayDescriptors(records) {
+			descs <- desc
+		}
+	}()
+	sched.Process(context.Background(), descs)
+}
+
+// replayDescriptors turns records into one descriptor per rate, ordered by
+// date within each currency and source, as if the monitor polled them day by
+// day. A single descriptor would only warm the processors up.
+func replayDescriptors(records []store.Record) []request.Descriptor {
+	type group struct {
+		currency string
+		source   string
+	}
+	var (
+		order  []group
+		groups = make(map[group][]request.Rate)
+	)
+	for _, r := range records {
+		g := group{currency: r.Currency, source: r.Source}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], request.Rate{Date: r.Date, Value: r.Value})
+	}
+
+	descs := make([]request.Descriptor, 0, len(records))
+	for _, g := range order {
+		rates := groups[g]
+		sort.Slice(rates, func(i, j int) bool {
+			return rates[i].Date.Before(rates[j].Date)
+		})
+		for _, rate := range rates {
+			descs = append(descs, request.Descriptor{
+				ID:              uuid.NewString(),
+				Target:          g.source,
+				Time:            time.Now(),
+				ValidStatusCode: true,
+				JSON:            true,
+				Valid:           true,
+				Payload: request.Currency{
+					Name:  g.currency,
+					Rates: []request.Rate{rate},
+				},
+				Backfill: true,
+			})
+		}
+	}
+	return descs
+}
+
+// newCSVStream appends rates newer than the ones already written to path.
+// The latest date written per currency is kept in states, so the rate history
+// repeated by every request is not appended again, even after a restart.
+func newCSVStream(path string, format csvrate.Format, states state.Store) (scheduler.Processor, func(), error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	format.Header = format.Header && info.Size() == 0
+	writer, err := processor.NewCSVWriter(f, format)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	dedup, err := processor.NewDeduplicator(writer, processor.WithDedupStore(states, "dedup/csv/"+path))
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return dedup, func() {
+		f.Close()
+	}, nil
+}
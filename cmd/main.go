@@ -2,57 +2,498 @@ package main
 
 import (
 	"context"
+	"flag"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/api"
 	"github.com/koenno/currency-price-monitor/client"
 	"github.com/koenno/currency-price-monitor/client/nbp"
+	"github.com/koenno/currency-price-monitor/metrics"
 	"github.com/koenno/currency-price-monitor/monitor"
+	"github.com/koenno/currency-price-monitor/notify"
 	"github.com/koenno/currency-price-monitor/processor"
+	"github.com/koenno/currency-price-monitor/processor/indicators"
+	"github.com/koenno/currency-price-monitor/processor/rules"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/rotate"
 	"github.com/koenno/currency-price-monitor/scheduler"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/koenno/currency-price-monitor/store"
+	"github.com/koenno/currency-price-monitor/store/sqlite"
+	"github.com/koenno/currency-price-monitor/stream"
 	"golang.org/x/text/currency"
 )
 
 const (
-	nbpDomain        = "api.nbp.pl"
-	requestsNo       = 10
-	requestsInterval = 5 * time.Second
+	nbpDomain             = "api.nbp.pl"
+	requestsNo            = 10
+	requestsInterval      = 5 * time.Second
+	maxConcurrentRequests = 4
+	requestsJitter        = 0.1
 
-	logPath = "log.txt"
+	logPath   = "log.txt"
+	statePath = "state.json"
+	ratesDir  = "rates"
 
-	currencyRangeStart = 4.50
-	currencyRangeEnd   = 4.70
+	currencyRangeStart    = 4.50
+	currencyRangeEnd      = 4.70
+	currencyRangeMargin   = 0.01
+	currencyRangeMinDwell = 24 * time.Hour
+
+	currencyChangePct          = 1.0
+	currencyChangeLookbackDays = 7
+
+	dryRunHistoryDays = 255
+)
+
+var (
+	monitoredCurrencies = []currency.Unit{currency.EUR, currency.USD, currency.CHF, currency.GBP}
+
+	rulesPath = flag.String("rules", "", "path to a JSON file with alert rules")
+	dryRun    = flag.Bool("dry-run", false, "validate rules, report which historic rates would trigger them and exit")
+
+	stdoutFormat      = flag.String("stdout-format", "text", "format of requests printed to stdout: text, logfmt, json, csv or template")
+	stdoutTemplate    = flag.String("stdout-template", "", "Go template of requests printed to stdout when -stdout-format is template")
+	logFormat         = flag.String("log-format", "text", "format of requests logged to the log file: text, logfmt, json, csv or template")
+	logTemplate       = flag.String("log-template", "", "Go template of logged requests when -log-format is template")
+	logMaxSize        = flag.Int64("log-max-size", 100, "size in MB after which the log file is rotated; 0 disables size rotation")
+	logRotateInterval = flag.Duration("log-rotate-interval", 0, "age after which the log file is rotated; 0 disables time rotation")
+	logMaxBackups     = flag.Int("log-max-backups", 7, "number of rotated log files kept; 0 keeps all")
+	logCompress       = flag.Bool("log-compress", true, "gzip rotated log files")
+
+	stdoutFilter = flag.String("stdout-filter", "", "requests printed to stdout: all when empty, failed, succeeded or comma separated error kinds")
+	logFilter    = flag.String("log-filter", "", "requests logged to the log file: all when empty, failed, succeeded or comma separated error kinds")
+
+	apiAddr     = flag.String("api-addr", "", "address serving the REST API on /api/v1, e.g. :8080; disabled when empty")
+	streamAddr  = flag.String("stream-addr", "", "address streaming live rates and alerts on /stream over SSE or WebSocket, e.g. :8081; disabled when empty")
+	metricsAddr = flag.String("metrics-addr", "", "address serving Prometheus metrics on /metrics, e.g. :9090; disabled when empty")
+
+	csvOut     = flag.String("csv-out", "", "path to a CSV file the collected rates are appended to")
+	csvFormat  = newCSVFormatFlags(flag.CommandLine, "csv-")
+	sqlitePath = flag.String("sqlite", "", "path to a SQLite database storing rates and requests instead of the rates directory")
+
+	alertCooldown  = flag.Duration("alert-cooldown", time.Hour, "time an unchanged alert stays muted after it was sent")
+	alertGroupWait = flag.Duration("alert-group-wait", 30*time.Second, "window collecting alerts into a single notification")
+
+	webhookURL    = flag.String("webhook-url", "", "URL receiving alerts as generic JSON")
+	webhookSecret = flag.String("webhook-secret", "", "secret used to sign generic JSON alerts with HMAC-SHA256")
+	slackURL      = flag.String("slack-url", "", "Slack incoming webhook URL receiving alerts")
+	teamsURL      = flag.String("teams-url", "", "Microsoft Teams incoming webhook URL receiving alerts")
+
+	smtpHost     = flag.String("smtp-host", "", "SMTP server host used to email alerts")
+	smtpPort     = flag.Int("smtp-port", 587, "SMTP server port")
+	smtpUser     = flag.String("smtp-user", "", "SMTP username; authentication is skipped when empty")
+	smtpPassword = flag.String("smtp-password", "", "SMTP password")
+	smtpFrom     = flag.String("smtp-from", "", "sender address of alert emails")
+	smtpTo       = flag.String("smtp-to", "", "comma separated recipients of alert emails")
+	smtpStartTLS = flag.Bool("smtp-starttls", true, "require STARTTLS before authenticating")
+	emailDigest  = flag.Duration("email-digest", 15*time.Minute, "window collecting alerts into a single email; 0 sends each alert immediately")
 )
 
 func main() {
-	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	flag.Parse()
+
+	var alertRules []rules.Rule
+	if *rulesPath != "" {
+		loaded, err := rules.LoadFile(*rulesPath)
+		if err != nil {
+			log.Fatalf("failed to load rules: %v", err)
+		}
+		if err := rules.Validate(loaded); err != nil {
+			log.Fatalf("invalid rules: %v", err)
+		}
+		alertRules = loaded
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "export":
+		runExport(flag.Args()[1:])
+		return
+	case "import":
+		runImport(flag.Args()[1:], alertRules)
+		return
+	default:
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
+
+	if *dryRun {
+		runDryRun(alertRules)
+		return
+	}
+
+	logOpts := []rotate.Option{
+		rotate.WithMaxSize(*logMaxSize << 20),
+		rotate.WithInterval(*logRotateInterval),
+		rotate.WithMaxBackups(*logMaxBackups),
+		rotate.WithCompression(*logCompress),
+	}
+	if *logFormat == "csv" {
+		logOpts = append(logOpts, rotate.WithHeader(processor.CSVHeader()))
+	}
+	logFile, err := rotate.Open(logPath, logOpts...)
 	if err != nil {
 		log.Fatalf("failed to open a file %s: %v", logPath, err)
 	}
+	defer logFile.Close()
+	go reopenOnHangup(logFile)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	mainClient := client.New[nbp.CurrencyResponse](nbp.NewConverter())
 
-	nbpClient := nbp.NewCurrencyClient(nbpDomain)
-	nbpReq, err := nbpClient.NewRequest(ctx, nbp.WithCurrency(currency.EUR), nbp.WithHistory(100))
+	rateStore, storageOpts, err := openRateStore()
 	if err != nil {
-		log.Fatalf("failed to create NBP request: %v", err)
+		log.Fatalf("failed to open rate store: %v", err)
 	}
+	defer rateStore.Close()
 
-	monitorSvc := monitor.New(mainClient, nbpReq)
-	requestsPipe := monitorSvc.Start(ctx, requestsNo, requestsInterval)
+	stateStore, err := state.NewFileStore(statePath)
+	if err != nil {
+		log.Fatalf("failed to open state store: %v", err)
+	}
+	alertManager, flushAlerts := newAlertManager(stateStore)
+	defer flushAlerts()
 
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	writer := processor.NewWriter[nbp.CurrencyResponse](multiWriter)
-	currencyIntervalWriter := processor.NewCurrencyIntervalNotifier(os.Stdout,
-		processor.ClosedInterval{A: currencyRangeStart, B: currencyRangeEnd})
+	nbpClient := nbp.NewCurrencyClient(nbpDomain)
+	monitorSvc := monitor.NewMulti(maxConcurrentRequests)
+	for _, unit := range monitoredCurrencies {
+		unit := unit
+		nbpReq, err := nbpClient.NewRequest(ctx, nbp.WithCurrency(unit), nbp.WithHistory(100))
+		if err != nil {
+			log.Fatalf("failed to create NBP request for %v: %v", unit, err)
+		}
+		err = monitorSvc.Add(monitor.Target{
+			Name:           "nbp-" + strings.ToLower(unit.String()),
+			Requester:      mainClient,
+			Request:        nbpReq,
+			RequestsNumber: requestsNo,
+			Interval:       requestsInterval,
+			Options: []monitor.Option{
+				monitor.WithJitter(requestsJitter),
+				monitor.WithBackfill(monitor.Backfill{
+					Currency: unit.String(),
+					Finder:   rateStore,
+					Requests: func(ctx context.Context, from, to time.Time) (*http.Request, error) {
+						return nbpClient.NewRequest(ctx, nbp.WithCurrency(unit), nbp.WithDateRange(from, to))
+					},
+					ChunkDays: nbp.MaxRangeDays,
+				}),
+			},
+		})
+		if err != nil {
+			log.Fatalf("failed to add monitor target for %v: %v", unit, err)
+		}
+	}
+	requestsPipe := monitorSvc.Start(ctx)
 
-	sched := scheduler.NewScheduler()
-	sched.Register(writer)
-	sched.Register(currencyIntervalWriter)
+	var taps []tap
+	if collector := serveMetrics(); collector != nil {
+		taps = append(taps, collector)
+	}
+	if server := serveAPI(rateStore, monitorSvc, alertManager); server != nil {
+		taps = append(taps, server)
+	}
+	if broadcaster := serveStream(); broadcaster != nil {
+		taps = append(taps, broadcaster)
+	}
+	sched := newScheduler(alertRules, stateStore, alertManager, rateStore, storageOpts, taps)
+	sched.Register(newWriter(os.Stdout, *stdoutFormat, *stdoutTemplate, *stdoutFilter), scheduler.WithName("stdout"))
+	sched.Register(newWriter(logFile, *logFormat, *logTemplate, *logFilter), scheduler.WithName("log"))
+	if *csvOut != "" {
+		csvWriter, closeCSV, err := newCSVStream(*csvOut, csvFormat.format(), stateStore)
+		if err != nil {
+			log.Fatalf("failed to open CSV output: %v", err)
+		}
+		defer closeCSV()
+		sched.Register(csvWriter, scheduler.WithName("csv"))
+	}
 	sched.Process(ctx, requestsPipe)
 }
+
+func runDryRun(alertRules []rules.Rule) {
+	ctx := context.Background()
+	mainClient := client.New[nbp.CurrencyResponse](nbp.NewConverter())
+	nbpClient := nbp.NewCurrencyClient(nbpDomain)
+
+	var histories []request.Currency
+	for _, unit := range monitoredCurrencies {
+		nbpReq, err := nbpClient.NewRequest(ctx, nbp.WithCurrency(unit), nbp.WithHistory(dryRunHistoryDays))
+		if err != nil {
+			log.Fatalf("failed to create NBP request for %v: %v", unit, err)
+		}
+		desc, err := mainClient.Process(nbpReq)
+		if err != nil {
+			log.Fatalf("failed to fetch history of %v: %v", unit, err)
+		}
+		histories = append(histories, desc.Payload)
+	}
+
+	matches, err := rules.DryRun(alertRules, histories...)
+	for _, m := range matches {
+		m.WriteTo(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("rules evaluation failed: %v", err)
+	}
+}
+
+func newScheduler(alertRules []rules.Rule, stateStore state.Store, alertSink alert.Sink, rates store.RateStore,
+	storageOpts []processor.StorageOption, taps []tap) *scheduler.Scheduler {
+	var schedOpts []scheduler.Option
+	for _, t := range taps {
+		alertSink = t.AlertSink(alertSink)
+		if observer, ok := t.(scheduler.Observer); ok {
+			schedOpts = append(schedOpts, scheduler.WithObserver(observer))
+		}
+	}
+	thresholdAlerter := processor.NewThresholdAlerter(alertSink, processor.Threshold{
+		Currency: currency.EUR.String(),
+		Band:     processor.ClosedInterval{A: currencyRangeStart, B: currencyRangeEnd},
+		Margin:   currencyRangeMargin,
+		MinDwell: currencyRangeMinDwell,
+	}, processor.WithThresholdStore(stateStore))
+
+	changeAlerter := processor.NewChangeAlerter(alertSink, processor.ChangeThreshold{
+		Percentage:   currencyChangePct,
+		LookbackDays: currencyChangeLookbackDays,
+	}, processor.WithChangeStore(stateStore))
+
+	anomalyDetector := processor.NewAnomalyDetector(alertSink, processor.AnomalyConfig{
+		Method: processor.AnomalyMAD,
+	}, processor.WithAnomalyStore(stateStore))
+
+	indicatorsProcessor := indicators.New(indicators.DefaultConfig(), indicators.WithAlerts(alertSink),
+		indicators.WithStore(stateStore))
+
+	dedupThresholdAlerter, err := processor.NewDeduplicator(thresholdAlerter,
+		processor.WithDedupStore(stateStore, "dedup/threshold"))
+	if err != nil {
+		log.Fatalf("failed to create deduplicator: %v", err)
+	}
+
+	sched := scheduler.NewScheduler(schedOpts...)
+	for _, t := range taps {
+		sched.Register(t)
+	}
+	if len(alertRules) > 0 {
+		rulesEngine, err := rules.New(alertSink, alertRules, rules.WithStore(stateStore))
+		if err != nil {
+			log.Fatalf("failed to create rules engine: %v", err)
+		}
+		sched.Register(rulesEngine)
+	}
+	sched.Register(processor.NewStorage(rates, storageOpts...))
+	sched.Register(dedupThresholdAlerter, scheduler.WithName("threshold"))
+	sched.Register(changeAlerter)
+	sched.Register(indicatorsProcessor)
+	sched.Register(anomalyDetector)
+	return sched
+}
+
+// tap is a processor that also sees every alert raised by the processors.
+type tap interface {
+	scheduler.Processor
+	AlertSink(next alert.Sink) alert.Sink
+}
+
+// serveAPI serves the REST API on -api-addr; it returns nil when the API is disabled.
+func serveAPI(rates store.RateStore, monitors api.Monitors, silences api.Silences) *api.Server {
+	if *apiAddr == "" {
+		return nil
+	}
+	server := api.NewServer(api.WithRates(rates), api.WithMonitors(monitors), api.WithSilences(silences),
+		api.WithIndicators(indicators.DefaultConfig()))
+	go func() {
+		if err := http.ListenAndServe(*apiAddr, server); err != nil {
+			log.Fatalf("failed to serve API: %v", err)
+		}
+	}()
+	return server
+}
+
+// serveStream pushes rates and alerts on -stream-addr; it returns nil when streaming is disabled.
+func serveStream() *stream.Broadcaster {
+	if *streamAddr == "" {
+		return nil
+	}
+	broadcaster := stream.NewBroadcaster()
+	mux := http.NewServeMux()
+	mux.Handle("/stream", broadcaster)
+	go func() {
+		if err := http.ListenAndServe(*streamAddr, mux); err != nil {
+			log.Fatalf("failed to serve stream: %v", err)
+		}
+	}()
+	return broadcaster
+}
+
+// serveMetrics exposes /metrics on -metrics-addr; it returns nil when metrics are disabled.
+func serveMetrics() *metrics.Collector {
+	if *metricsAddr == "" {
+		return nil
+	}
+	registry := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	go func() {
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			log.Fatalf("failed to serve metrics: %v", err)
+		}
+	}()
+	return metrics.NewCollector(registry)
+}
+
+func newWriter(out io.Writer, format, tmpl, filter string) *processor.Writer {
+	var formatter processor.Formatter
+	switch format {
+	case "text":
+		formatter = processor.TextFormatter{}
+	case "logfmt":
+		formatter = processor.LogfmtFormatter{}
+	case "json":
+		formatter = processor.JSONFormatter{}
+	case "csv":
+		formatter = processor.NewCSVFormatter(isEmpty(out))
+	case "template":
+		templateFormatter, err := processor.NewTemplateFormatter(tmpl)
+		if err != nil {
+			log.Fatalf("invalid output template: %v", err)
+		}
+		formatter = templateFormatter
+	default:
+		log.Fatalf("unknown output format %q", format)
+	}
+	opts := []processor.WriterOption{processor.WithFormatter(formatter)}
+	if filter != "" {
+		opts = append(opts, processor.WithFilter(newPredicate(filter)))
+	}
+	return processor.NewWriter(out, opts...)
+}
+
+func newPredicate(filter string) processor.Predicate {
+	switch filter {
+	case "failed":
+		return processor.Failed
+	case "succeeded":
+		return processor.Succeeded
+	}
+	var kinds []request.ErrorKind
+	for _, k := range strings.Split(filter, ",") {
+		kind := request.ErrorKind(strings.TrimSpace(k))
+		switch kind {
+		case request.ErrorTransport, request.ErrorHTTPStatus, request.ErrorPayload, request.ErrorDecode:
+			kinds = append(kinds, kind)
+		default:
+			log.Fatalf("unknown request filter %q", k)
+		}
+	}
+	return processor.WithErrorKinds(kinds...)
+}
+
+// isEmpty reports whether out is a file without content yet, so a CSV header
+// is not repeated when the file is appended to. The log file gets its header
+// from rotate.WithHeader, at the start of every rotated file.
+func isEmpty(out io.Writer) bool {
+	switch f := out.(type) {
+	case *rotate.Writer:
+		return false
+	case *os.File:
+		info, err := f.Stat()
+		return err != nil || !info.Mode().IsRegular() || info.Size() == 0
+	default:
+		return true
+	}
+}
+
+// reopenOnHangup reopens the log file on SIGHUP, after it was moved away by logrotate.
+func reopenOnHangup(logFile *rotate.Writer) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if err := logFile.Reopen(); err != nil {
+			log.Printf("failed to reopen %s: %v", logPath, err)
+		}
+	}
+}
+
+type rateStore interface {
+	store.RateStore
+	monitor.LastDateFinder
+	io.Closer
+}
+
+func openRateStore() (rateStore, []processor.StorageOption, error) {
+	if *sqlitePath == "" {
+		fileStore, err := store.OpenFileStore(ratesDir)
+		return fileStore, nil, err
+	}
+	sqliteStore, err := sqlite.Open(*sqlitePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sqliteStore, []processor.StorageOption{processor.WithDescriptorLog(sqliteStore)}, nil
+}
+
+// newAlertManager routes alerts to the configured notifiers; the returned
+// func sends alerts still waiting for their group or digest.
+func newAlertManager(states state.Store) (*alert.Manager, func()) {
+	flush := func() {}
+	dispatcher := notify.NewDispatcher()
+	dispatcher.Route(alert.NewWriterSink(os.Stdout))
+	if *webhookURL != "" {
+		var opts []notify.WebhookOption
+		if *webhookSecret != "" {
+			opts = append(opts, notify.WithHMAC([]byte(*webhookSecret)))
+		}
+		dispatcher.Route(notify.NewWebhook(*webhookURL, opts...))
+	}
+	if *slackURL != "" {
+		dispatcher.Route(notify.NewSlack(*slackURL), notify.MinSeverity(alert.SeverityWarning))
+	}
+	if *teamsURL != "" {
+		dispatcher.Route(notify.NewTeams(*teamsURL), notify.MinSeverity(alert.SeverityWarning))
+	}
+	if *smtpHost != "" {
+		email, err := notify.NewEmail(notify.SMTPConfig{
+			Host:     *smtpHost,
+			Port:     *smtpPort,
+			Username: *smtpUser,
+			Password: *smtpPassword,
+			From:     *smtpFrom,
+			To:       strings.Split(*smtpTo, ","),
+			StartTLS: *smtpStartTLS,
+		}, notify.WithDigestWindow(*emailDigest))
+		if err != nil {
+			log.Fatalf("failed to configure email alerts: %v", err)
+		}
+		dispatcher.Route(email, notify.MinSeverity(alert.SeverityWarning))
+		flush = func() {
+			if err := email.Flush(context.Background()); err != nil {
+				log.Printf("failed to send pending alert emails: %v", err)
+			}
+		}
+	}
+	manager, err := alert.NewManager(dispatcher,
+		alert.WithCooldown(*alertCooldown),
+		alert.WithGroupWait(*alertGroupWait),
+		alert.WithManagerStore(states))
+	if err != nil {
+		log.Fatalf("failed to create alert manager: %v", err)
+	}
+	return manager, func() {
+		if err := manager.Flush(context.Background()); err != nil {
+			log.Printf("failed to send pending alerts: %v", err)
+		}
+		flush()
+	}
+}
@@ -0,0 +1,90 @@
+package csvrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFormat   = errors.New("invalid csv format")
+	ErrMalformedRecord = errors.New("malformed csv record")
+)
+
+type Column string
+
+const (
+	ColumnDate     Column = "date"
+	ColumnCurrency Column = "currency"
+	ColumnSource   Column = "source"
+	ColumnValue    Column = "value"
+)
+
+var knownColumns = map[Column]bool{
+	ColumnDate:     true,
+	ColumnCurrency: true,
+	ColumnSource:   true,
+	ColumnValue:    true,
+}
+
+type Format struct {
+	Columns          []Column
+	Delimiter        rune
+	DecimalSeparator rune
+	DateLayout       string
+	Header           bool
+}
+
+func DefaultFormat() Format {
+	return Format{
+		Columns:          []Column{ColumnDate, ColumnCurrency, ColumnSource, ColumnValue},
+		Delimiter:        ',',
+		DecimalSeparator: '.',
+		DateLayout:       time.DateOnly,
+		Header:           true,
+	}
+}
+
+func ParseColumns(s string) ([]Column, error) {
+	var columns []Column
+	for _, name := range strings.Split(s, ",") {
+		column := Column(strings.ToLower(strings.TrimSpace(name)))
+		if !knownColumns[column] {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFormat, name)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func (f Format) Validate() error {
+	return validateColumns(f.Columns, f)
+}
+
+func validateColumns(columns []Column, f Format) error {
+	seen := make(map[Column]bool, len(columns))
+	for _, c := range columns {
+		if !knownColumns[c] {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidFormat, c)
+		}
+		if seen[c] {
+			return fmt.Errorf("%w: duplicated column %q", ErrInvalidFormat, c)
+		}
+		seen[c] = true
+	}
+	if !seen[ColumnDate] || !seen[ColumnValue] {
+		return fmt.Errorf("%w: date and value columns are required", ErrInvalidFormat)
+	}
+	switch f.Delimiter {
+	case 0, '"', '\r', '\n':
+		return fmt.Errorf("%w: delimiter %q", ErrInvalidFormat, f.Delimiter)
+	}
+	if f.DecimalSeparator == 0 || f.DecimalSeparator == f.Delimiter {
+		return fmt.Errorf("%w: decimal separator %q", ErrInvalidFormat, f.DecimalSeparator)
+	}
+	if f.DateLayout == "" {
+		return fmt.Errorf("%w: empty date layout", ErrInvalidFormat)
+	}
+	return nil
+}
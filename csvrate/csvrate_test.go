@@ -0,0 +1,140 @@
+package csvrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func TestShouldWriteRatesInDefaultFormat(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut, err := NewWriter(out, DefaultFormat())
+	assert.NoError(t, err)
+
+	// when
+	errFirst := sut.Write([]store.Record{{Currency: "EUR", Source: "nbp", Date: date("2023-10-02"), Value: 4.6}})
+	errSecond := sut.Write([]store.Record{{Currency: "EUR", Source: "nbp", Date: date("2023-10-03"), Value: 4.6123}})
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Equal(t, "date,currency,source,value\n2023-10-02,EUR,nbp,4.6\n2023-10-03,EUR,nbp,4.6123\n", out.String())
+}
+
+func TestShouldWriteRatesInSpreadsheetFormat(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut, err := NewWriter(out, Format{
+		Columns:          []Column{ColumnDate, ColumnValue},
+		Delimiter:        ';',
+		DecimalSeparator: ',',
+		DateLayout:       "02.01.2006",
+	})
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Write([]store.Record{{Currency: "EUR", Date: date("2023-10-03"), Value: 4.6123}})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "03.10.2023;4,6123\n", out.String())
+}
+
+func TestShouldReadWrittenRates(t *testing.T) {
+	// given
+	format := DefaultFormat()
+	format.Delimiter = ';'
+	format.DecimalSeparator = ','
+	expected := []store.Record{
+		{Currency: "EUR", Source: "nbp", Date: date("2023-10-02"), Value: 4.6},
+		{Currency: "USD", Source: "nbp", Date: date("2023-10-03"), Value: 4.3512},
+	}
+	out := &bytes.Buffer{}
+	writer, err := NewWriter(out, format)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(expected))
+
+	// when
+	sut, err := NewReader(out, format)
+	assert.NoError(t, err)
+	actual, err := sut.ReadAll()
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestShouldTakeColumnsFromHeaderAndApplyDefaults(t *testing.T) {
+	// given
+	in := strings.NewReader("value,date\n4.61,2023-10-03\n")
+	sut, err := NewReader(in, DefaultFormat(), WithDefaultCurrency("EUR"), WithDefaultSource("archive"))
+	assert.NoError(t, err)
+
+	// when
+	actual, err := sut.ReadAll()
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []store.Record{{Currency: "EUR", Source: "archive", Date: date("2023-10-03"), Value: 4.61}}, actual)
+}
+
+func TestShouldReportMalformedLine(t *testing.T) {
+	// given
+	in := strings.NewReader("date,currency,value\n2023-10-02,EUR,4.60\n2023-10-03,EUR,abc\n")
+	sut, err := NewReader(in, DefaultFormat())
+	assert.NoError(t, err)
+
+	// when
+	_, err = sut.ReadAll()
+
+	// then
+	assert.ErrorIs(t, err, ErrMalformedRecord)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestShouldRejectInvalidFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Format)
+	}{
+		{name: "missing value column", modify: func(f *Format) { f.Columns = []Column{ColumnDate} }},
+		{name: "duplicated column", modify: func(f *Format) { f.Columns = []Column{ColumnDate, ColumnValue, ColumnDate} }},
+		{name: "decimal separator equal to delimiter", modify: func(f *Format) { f.DecimalSeparator = ',' }},
+		{name: "quote delimiter", modify: func(f *Format) { f.Delimiter = '"' }},
+		{name: "empty date layout", modify: func(f *Format) { f.DateLayout = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			format := DefaultFormat()
+			tt.modify(&format)
+
+			// when
+			err := format.Validate()
+
+			// then
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+		})
+	}
+}
+
+func TestShouldParseColumns(t *testing.T) {
+	// when
+	columns, err := ParseColumns("Date, value")
+	_, errUnknown := ParseColumns("date,price")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []Column{ColumnDate, ColumnValue}, columns)
+	assert.ErrorIs(t, errUnknown, ErrInvalidFormat)
+}
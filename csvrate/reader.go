@@ -0,0 +1,152 @@
+package csvrate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/store"
+)
+
+type readerOptions struct {
+	currency string
+	source   string
+}
+
+type ReaderOption func(*readerOptions)
+
+func WithDefaultCurrency(currency string) ReaderOption {
+	return func(o *readerOptions) {
+		o.currency = currency
+	}
+}
+
+func WithDefaultSource(source string) ReaderOption {
+	return func(o *readerOptions) {
+		o.source = source
+	}
+}
+
+type Reader struct {
+	in      *csv.Reader
+	format  Format
+	options readerOptions
+	columns []Column
+	line    int
+}
+
+func NewReader(in io.Reader, format Format, opts ...ReaderOption) (*Reader, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	cfg := readerOptions{}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	r := csv.NewReader(in)
+	r.Comma = format.Delimiter
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	return &Reader{
+		in:      r,
+		format:  format,
+		options: cfg,
+		columns: format.Columns,
+	}, nil
+}
+
+// Read returns the next record or io.EOF. With a header the columns are
+// taken from it, so files exported with other column orders load as well.
+func (r *Reader) Read() (store.Record, error) {
+	if r.format.Header && r.line == 0 {
+		header, err := r.next()
+		if err != nil {
+			return store.Record{}, err
+		}
+		columns, err := ParseColumns(strings.Join(header, ","))
+		if err == nil {
+			err = validateColumns(columns, r.format)
+		}
+		if err != nil {
+			return store.Record{}, fmt.Errorf("%w: line 1: %v", ErrMalformedRecord, err)
+		}
+		r.columns = columns
+	}
+
+	fields, err := r.next()
+	if err != nil {
+		return store.Record{}, err
+	}
+	if len(fields) != len(r.columns) {
+		return store.Record{}, fmt.Errorf("%w: line %d: expected %d fields, got %d",
+			ErrMalformedRecord, r.line, len(r.columns), len(fields))
+	}
+	record := store.Record{
+		Currency: r.options.currency,
+		Source:   r.options.source,
+	}
+	for i, c := range r.columns {
+		if err := r.set(&record, c, strings.TrimSpace(fields[i])); err != nil {
+			return store.Record{}, fmt.Errorf("%w: line %d: %v", ErrMalformedRecord, r.line, err)
+		}
+	}
+	if record.Currency == "" {
+		return store.Record{}, fmt.Errorf("%w: line %d: missing currency", ErrMalformedRecord, r.line)
+	}
+	return record, nil
+}
+
+func (r *Reader) ReadAll() ([]store.Record, error) {
+	var records []store.Record
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func (r *Reader) next() ([]string, error) {
+	fields, err := r.in.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrMalformedRecord, err)
+	}
+	r.line, _ = r.in.FieldPos(0)
+	return fields, nil
+}
+
+func (r *Reader) set(record *store.Record, c Column, field string) error {
+	switch c {
+	case ColumnDate:
+		date, err := time.Parse(r.format.DateLayout, field)
+		if err != nil {
+			return fmt.Errorf("invalid date %q", field)
+		}
+		record.Date = date
+	case ColumnCurrency:
+		record.Currency = strings.ToUpper(field)
+	case ColumnSource:
+		record.Source = field
+	case ColumnValue:
+		if r.format.DecimalSeparator != '.' {
+			field = strings.Replace(field, string(r.format.DecimalSeparator), ".", 1)
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q", field)
+		}
+		record.Value = value
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package csvrate
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/koenno/currency-price-monitor/store"
+)
+
+type Writer struct {
+	out           *csv.Writer
+	format        Format
+	headerWritten bool
+}
+
+func NewWriter(out io.Writer, format Format) (*Writer, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	w := csv.NewWriter(out)
+	w.Comma = format.Delimiter
+	return &Writer{
+		out:    w,
+		format: format,
+	}, nil
+}
+
+func (w *Writer) Write(records []store.Record) error {
+	if w.format.Header && !w.headerWritten {
+		header := make([]string, len(w.format.Columns))
+		for i, c := range w.format.Columns {
+			header[i] = string(c)
+		}
+		if err := w.out.Write(header); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	row := make([]string, len(w.format.Columns))
+	for _, r := range records {
+		for i, c := range w.format.Columns {
+			row[i] = w.field(c, r)
+		}
+		if err := w.out.Write(row); err != nil {
+			return err
+		}
+	}
+	w.out.Flush()
+	return w.out.Error()
+}
+
+func (w *Writer) field(c Column, r store.Record) string {
+	switch c {
+	case ColumnDate:
+		return r.Date.Format(w.format.DateLayout)
+	case ColumnCurrency:
+		return r.Currency
+	case ColumnSource:
+		return r.Source
+	case ColumnValue:
+		value := strconv.FormatFloat(r.Value, 'f', -1, 64)
+		if w.format.DecimalSeparator != '.' {
+			value = strings.Replace(value, ".", string(w.format.DecimalSeparator), 1)
+		}
+		return value
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+const (
+	namespace = "currency_monitor_"
+
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+// Collector turns descriptors, processor timings and alerts into metrics.
+// It is a scheduler processor and a scheduler observer at the same time.
+type Collector struct {
+	rate              Gauge
+	rateDate          Gauge
+	requests          Counter
+	requestDuration   Histogram
+	processorDuration Histogram
+	processorErrors   Counter
+	alerts            Counter
+}
+
+func NewCollector(registry *Registry) *Collector {
+	return &Collector{
+		rate: registry.NewGauge(namespace+"rate",
+			"Latest rate of a currency.", "currency"),
+		rateDate: registry.NewGauge(namespace+"rate_date_seconds",
+			"Date of the latest rate of a currency as a Unix timestamp.", "currency"),
+		requests: registry.NewCounter(namespace+"requests_total",
+			"Requests by target and outcome; failed requests are labelled with their error kind.", "target", "outcome"),
+		requestDuration: registry.NewHistogram(namespace+"request_duration_seconds",
+			"Duration of requests.", DefaultBuckets, "target"),
+		processorDuration: registry.NewHistogram(namespace+"processor_duration_seconds",
+			"Time a processor took to handle a descriptor.", DefaultBuckets, "processor"),
+		processorErrors: registry.NewCounter(namespace+"processor_errors_total",
+			"Descriptors a processor failed to handle.", "processor"),
+		alerts: registry.NewCounter(namespace+"alerts_total",
+			"Alerts raised by processors.", "name", "severity", "resolved"),
+	}
+}
+
+func (c *Collector) Process(ctx context.Context, desc request.Descriptor) error {
+	c.requests.Inc(desc.Target, outcome(desc))
+	if desc.Duration > 0 {
+		c.requestDuration.Observe(desc.Duration.Seconds(), desc.Target)
+	}
+	if desc.Backfill || len(desc.Payload.Rates) == 0 {
+		return nil
+	}
+	latest := desc.Payload.Rates[0]
+	for _, r := range desc.Payload.Rates[1:] {
+		if r.Date.After(latest.Date) {
+			latest = r
+		}
+	}
+	c.rate.Set(latest.Value, desc.Payload.Name)
+	c.rateDate.Set(float64(latest.Date.Unix()), desc.Payload.Name)
+	return nil
+}
+
+func (c *Collector) ObserveProcessing(processor string, duration time.Duration, err error) {
+	c.processorDuration.Observe(duration.Seconds(), processor)
+	if err != nil {
+		c.processorErrors.Inc(processor)
+	}
+}
+
+// AlertSink counts alerts before passing them to next.
+func (c *Collector) AlertSink(next alert.Sink) alert.Sink {
+	return alertCounter{
+		next:      next,
+		collector: c,
+	}
+}
+
+type alertCounter struct {
+	next      alert.Sink
+	collector *Collector
+}
+
+func (s alertCounter) Notify(ctx context.Context, events []alert.Event) error {
+	for _, e := range events {
+		resolved := "false"
+		if e.Resolved {
+			resolved = "true"
+		}
+		s.collector.alerts.Inc(e.Name, string(e.Severity), resolved)
+	}
+	return s.next.Notify(ctx, events)
+}
+
+func outcome(desc request.Descriptor) string {
+	switch {
+	case desc.ErrorKind != "":
+		return string(desc.ErrorKind)
+	case desc.Failed():
+		return outcomeError
+	default:
+		return outcomeSuccess
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func TestShouldExportRequestsAndLatestRates(t *testing.T) {
+	// given
+	registry := NewRegistry()
+	sut := NewCollector(registry)
+	succeeded := request.Descriptor{
+		Target:   "EUR",
+		Duration: 200 * time.Millisecond,
+		Payload: request.Currency{
+			Name: "EUR",
+			Rates: []request.Rate{
+				{Date: date("2023-10-03"), Value: 4.61},
+				{Date: date("2023-10-02"), Value: 4.6},
+			},
+		},
+	}
+	backfill := request.Descriptor{
+		Target:   "EUR",
+		Backfill: true,
+		Payload:  request.Currency{Name: "EUR", Rates: []request.Rate{{Date: date("2023-09-01"), Value: 4.4}}},
+	}
+	failed := request.Descriptor{Target: "EUR", ErrorKind: request.ErrorHTTPStatus}
+	out := &bytes.Buffer{}
+
+	// when
+	errSucceeded := sut.Process(context.Background(), succeeded)
+	errBackfill := sut.Process(context.Background(), backfill)
+	errFailed := sut.Process(context.Background(), failed)
+	registry.WriteTo(out)
+
+	// then
+	assert.NoError(t, errSucceeded)
+	assert.NoError(t, errBackfill)
+	assert.NoError(t, errFailed)
+	assert.Contains(t, out.String(), "currency_monitor_rate{currency=\"EUR\"} 4.61\n")
+	assert.Contains(t, out.String(), "currency_monitor_rate_date_seconds{currency=\"EUR\"} 1.6962912e+09\n")
+	assert.Contains(t, out.String(), "currency_monitor_requests_total{target=\"EUR\",outcome=\"success\"} 2\n")
+	assert.Contains(t, out.String(), "currency_monitor_requests_total{target=\"EUR\",outcome=\"http_status\"} 1\n")
+	assert.Contains(t, out.String(), "currency_monitor_request_duration_seconds_bucket{target=\"EUR\",le=\"0.25\"} 1\n")
+	assert.Contains(t, out.String(), "currency_monitor_request_duration_seconds_count{target=\"EUR\"} 1\n")
+}
+
+func TestShouldExportProcessorTimingsAndErrors(t *testing.T) {
+	// given
+	registry := NewRegistry()
+	sut := NewCollector(registry)
+	out := &bytes.Buffer{}
+
+	// when
+	sut.ObserveProcessing("processor.Writer", time.Millisecond, nil)
+	sut.ObserveProcessing("processor.Writer", 2*time.Millisecond, errors.New("failure"))
+	registry.WriteTo(out)
+
+	// then
+	assert.Contains(t, out.String(), "currency_monitor_processor_duration_seconds_count{processor=\"processor.Writer\"} 2\n")
+	assert.Contains(t, out.String(), "currency_monitor_processor_errors_total{processor=\"processor.Writer\"} 1\n")
+}
+
+func TestShouldCountAlertsPassedToNextSink(t *testing.T) {
+	// given
+	registry := NewRegistry()
+	sinkMock := mocks.NewSink(t)
+	sut := NewCollector(registry).AlertSink(sinkMock)
+	events := []alert.Event{
+		{Name: "threshold", Severity: alert.SeverityWarning},
+		{Name: "threshold", Severity: alert.SeverityWarning, Resolved: true},
+	}
+	out := &bytes.Buffer{}
+
+	sinkMock.EXPECT().Notify(mock.Anything, events).Return(nil).Once()
+
+	// when
+	err := sut.Notify(context.Background(), events)
+	registry.WriteTo(out)
+
+	// then
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "currency_monitor_alerts_total{name=\"threshold\",severity=\"warning\",resolved=\"false\"} 1\n")
+	assert.Contains(t, out.String(), "currency_monitor_alerts_total{name=\"threshold\",severity=\"warning\",resolved=\"true\"} 1\n")
+}
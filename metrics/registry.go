@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+// Registry keeps metric families and writes them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mtx      sync.Mutex
+	families []*family
+	names    map[string]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]struct{}),
+	}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+
+	mtx    sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func (r *Registry) register(name, help string, k kind, buckets []float64, labels []string) *family {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, exists := r.names[name]; exists {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	r.names[name] = struct{}{}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    k,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(f.buckets)),
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+type Counter struct {
+	family *family
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) Counter {
+	return Counter{family: r.register(name, help, kindCounter, nil, labels)}
+}
+
+func (c Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.family.name))
+	}
+	c.family.mtx.Lock()
+	defer c.family.mtx.Unlock()
+	c.family.with(labelValues).value += v
+}
+
+type Gauge struct {
+	family *family
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) Gauge {
+	return Gauge{family: r.register(name, help, kindGauge, nil, labels)}
+}
+
+func (g Gauge) Set(v float64, labelValues ...string) {
+	g.family.mtx.Lock()
+	defer g.family.mtx.Unlock()
+	g.family.with(labelValues).value = v
+}
+
+type Histogram struct {
+	family *family
+}
+
+// NewHistogram registers a histogram with the given upper bounds, which
+// must be sorted; the +Inf bucket is implicit.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("histogram %s buckets are not sorted", name))
+	}
+	return Histogram{family: r.register(name, help, kindHistogram, buckets, labels)}
+}
+
+func (h Histogram) Observe(v float64, labelValues ...string) {
+	h.family.mtx.Lock()
+	defer h.family.mtx.Unlock()
+	s := h.family.with(labelValues)
+	for i, upper := range h.family.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mtx.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mtx.Unlock()
+
+	var sb strings.Builder
+	for _, f := range families {
+		f.writeTo(&sb)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.WriteTo(w)
+}
+
+func (f *family) writeTo(sb *strings.Builder) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if len(f.series) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(sb, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != kindHistogram {
+			writeSample(sb, f.name, f.labels, s.labelValues, "", "", s.value)
+			continue
+		}
+		for i, upper := range f.buckets {
+			writeSample(sb, f.name+"_bucket", f.labels, s.labelValues, "le", formatFloat(upper), float64(s.counts[i]))
+		}
+		writeSample(sb, f.name+"_bucket", f.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(sb, f.name+"_sum", f.labels, s.labelValues, "", "", s.sum)
+		writeSample(sb, f.name+"_count", f.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+func writeSample(sb *strings.Builder, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	sb.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		sb.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(sb, "%s=\"%s\"", l, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(sb, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(formatFloat(v))
+	sb.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldWriteTextExposition(t *testing.T) {
+	// given
+	sut := NewRegistry()
+	requests := sut.NewCounter("requests_total", "Requests by outcome.", "outcome")
+	rate := sut.NewGauge("rate", "Latest rate.\nPer currency.", "currency")
+	sut.NewCounter("unused_total", "Never incremented.")
+	requests.Inc("success")
+	requests.Add(2, "transport")
+	requests.Inc("success")
+	rate.Set(4.61, `E"U\R`)
+	out := &bytes.Buffer{}
+
+	// when
+	_, err := sut.WriteTo(out)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP requests_total Requests by outcome.
+# TYPE requests_total counter
+requests_total{outcome="success"} 2
+requests_total{outcome="transport"} 2
+# HELP rate Latest rate.\nPer currency.
+# TYPE rate gauge
+rate{currency="E\"U\\R"} 4.61
+`, out.String())
+}
+
+func TestShouldWriteHistogramBuckets(t *testing.T) {
+	// given
+	sut := NewRegistry()
+	duration := sut.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1})
+	duration.Observe(0.05)
+	duration.Observe(0.5)
+	duration.Observe(3)
+	out := &bytes.Buffer{}
+
+	// when
+	_, err := sut.WriteTo(out)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 3.55
+duration_seconds_count 3
+`, out.String())
+}
+
+func TestShouldServeMetricsOverHTTP(t *testing.T) {
+	// given
+	sut := NewRegistry()
+	sut.NewGauge("up", "Whether the monitor runs.").Set(1)
+	server := httptest.NewServer(sut)
+	defer server.Close()
+
+	// when
+	resp, err := http.Get(server.URL)
+
+	// then
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "up 1\n")
+}
+
+func TestShouldPanicOnMismatchedLabelsAndDuplicates(t *testing.T) {
+	// given
+	sut := NewRegistry()
+	counter := sut.NewCounter("requests_total", "Requests.", "outcome")
+
+	// then
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { sut.NewGauge("requests_total", "Duplicate.") })
+}
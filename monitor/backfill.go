@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	defaultBackfillDays = 93
+)
+
+//go:generate mockery --name=LastDateFinder --case underscore --with-expecter
+type LastDateFinder interface {
+	LastDate(ctx context.Context, currency string) (time.Time, bool, error)
+}
+
+type RangeRequestFunc func(ctx context.Context, from, to time.Time) (*http.Request, error)
+
+type Backfill struct {
+	Currency  string
+	Finder    LastDateFinder
+	Requests  RangeRequestFunc
+	ChunkDays uint
+	MaxDays   uint
+}
+
+type dateRange struct {
+	from time.Time
+	to   time.Time
+}
+
+func (m Monitor) backfill(ctx context.Context, cfg Backfill, output chan<- request.Descriptor) {
+	ranges, err := cfg.missingRanges(ctx, time.Now())
+	if err != nil {
+		slog.Error("backfill failed to determine missing dates", "currency", cfg.Currency, "error", err)
+		return
+	}
+	for _, r := range ranges {
+		if ctx.Err() != nil {
+			return
+		}
+		req, err := cfg.Requests(ctx, r.from, r.to)
+		if err != nil {
+			slog.Error("backfill failed to create a request", "currency", cfg.Currency, "error", err)
+			continue
+		}
+		desc, err := m.requester.Process(req)
+		if desc.StatusCode == http.StatusNotFound {
+			// NBP answers ranges without any published rate, e.g. holidays, with 404
+			slog.Debug("backfill found no rates", "currency", cfg.Currency, "from", r.from, "to", r.to)
+			continue
+		}
+		if err != nil {
+			slog.Error("backfill failed to process a request", "currency", cfg.Currency, "error", err)
+			desc.Error = err.Error()
+		}
+		desc.Backfill = true
+		output <- desc
+	}
+}
+
+func (cfg Backfill) missingRanges(ctx context.Context, now time.Time) ([]dateRange, error) {
+	maxDays := cfg.MaxDays
+	if maxDays == 0 {
+		maxDays = defaultBackfillDays
+	}
+	chunkDays := cfg.ChunkDays
+	if chunkDays == 0 {
+		chunkDays = maxDays
+	}
+
+	today := day(now)
+	from := today.AddDate(0, 0, -int(maxDays)+1)
+	last, found, err := cfg.Finder.LastDate(ctx, cfg.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		next := day(last).AddDate(0, 0, 1)
+		if next.After(from) {
+			from = next
+		}
+	}
+
+	var ranges []dateRange
+	for !from.After(today) {
+		to := from.AddDate(0, 0, int(chunkDays)-1)
+		if to.After(today) {
+			to = today
+		}
+		if r, ok := businessDays(from, to); ok {
+			ranges = append(ranges, r)
+		}
+		from = to.AddDate(0, 0, 1)
+	}
+	return ranges, nil
+}
+
+// businessDays narrows the range to start and end on a weekday, since no
+// rates are published on weekends.
+func businessDays(from, to time.Time) (dateRange, bool) {
+	for isWeekend(from) && !from.After(to) {
+		from = from.AddDate(0, 0, 1)
+	}
+	for isWeekend(to) && !to.Before(from) {
+		to = to.AddDate(0, 0, -1)
+	}
+	return dateRange{from: from, to: to}, !from.After(to)
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/monitor/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldSplitMissingDatesIntoChunks(t *testing.T) {
+	// given
+	finderMock := mocks.NewLastDateFinder(t)
+	now := time.Date(2023, 10, 10, 15, 0, 0, 0, time.UTC)
+	sut := Backfill{
+		Currency:  "EUR",
+		Finder:    finderMock,
+		ChunkDays: 4,
+		MaxDays:   30,
+	}
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(newDate("2023-10-01"), true, nil).Once()
+
+	// when
+	ranges, err := sut.missingRanges(context.Background(), now)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []dateRange{
+		{from: newDate("2023-10-02"), to: newDate("2023-10-05")},
+		{from: newDate("2023-10-06"), to: newDate("2023-10-09")},
+		{from: newDate("2023-10-10"), to: newDate("2023-10-10")},
+	}, ranges)
+}
+
+func TestShouldLimitBackfillToMaxDaysWhenNothingIsStored(t *testing.T) {
+	// given
+	finderMock := mocks.NewLastDateFinder(t)
+	now := time.Date(2023, 10, 10, 15, 0, 0, 0, time.UTC)
+	sut := Backfill{
+		Currency: "EUR",
+		Finder:   finderMock,
+		MaxDays:  10,
+	}
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(time.Time{}, false, nil).Once()
+
+	// when
+	ranges, err := sut.missingRanges(context.Background(), now)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []dateRange{
+		{from: newDate("2023-10-02"), to: newDate("2023-10-10")},
+	}, ranges)
+}
+
+func TestShouldSkipWeekendsInMissingRanges(t *testing.T) {
+	// given
+	finderMock := mocks.NewLastDateFinder(t)
+	now := time.Date(2023, 10, 16, 15, 0, 0, 0, time.UTC)
+	sut := Backfill{
+		Currency:  "EUR",
+		Finder:    finderMock,
+		ChunkDays: 2,
+	}
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(newDate("2023-10-12"), true, nil).Once()
+
+	// when
+	ranges, err := sut.missingRanges(context.Background(), now)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []dateRange{
+		{from: newDate("2023-10-13"), to: newDate("2023-10-13")},
+		{from: newDate("2023-10-16"), to: newDate("2023-10-16")},
+	}, ranges)
+}
+
+func TestShouldReturnNoRangesWhenUpToDate(t *testing.T) {
+	// given
+	finderMock := mocks.NewLastDateFinder(t)
+	now := time.Date(2023, 10, 10, 15, 0, 0, 0, time.UTC)
+	sut := Backfill{
+		Currency: "EUR",
+		Finder:   finderMock,
+	}
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(newDate("2023-10-10"), true, nil).Once()
+
+	// when
+	ranges, err := sut.missingRanges(context.Background(), now)
+
+	// then
+	assert.NoError(t, err)
+	assert.Empty(t, ranges)
+}
+
+func TestShouldEmitBackfillDescriptorsBeforeLivePolling(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	finderMock := mocks.NewLastDateFinder(t)
+	liveReq, _ := http.NewRequest(http.MethodGet, "some.domain.com/live", nil)
+	var requestedRanges []dateRange
+	backfill := Backfill{
+		Currency: "EUR",
+		Finder:   finderMock,
+		Requests: func(ctx context.Context, from, to time.Time) (*http.Request, error) {
+			requestedRanges = append(requestedRanges, dateRange{from: from, to: to})
+			return http.NewRequest(http.MethodGet, "some.domain.com/range", nil)
+		},
+		ChunkDays: 7,
+	}
+	sut := New(requesterMock, liveReq, WithBackfill(backfill))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lastStored := day(time.Now()).AddDate(0, 0, -14)
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(lastStored, true, nil).Once()
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil).Times(3)
+
+	// when
+	output, _ := sut.Start(ctx, 1, time.Minute)
+
+	// then
+	var descs []request.Descriptor
+	for i := 0; i < 3; i++ {
+		descs = append(descs, <-output)
+	}
+	assert.Len(t, requestedRanges, 2)
+	assert.True(t, descs[0].Backfill)
+	assert.True(t, descs[1].Backfill)
+	assert.False(t, descs[2].Backfill)
+}
+
+func TestShouldSkipBackfillRangesWithoutRates(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	finderMock := mocks.NewLastDateFinder(t)
+	liveReq, _ := http.NewRequest(http.MethodGet, "some.domain.com/live", nil)
+	backfill := Backfill{
+		Currency: "EUR",
+		Finder:   finderMock,
+		Requests: func(ctx context.Context, from, to time.Time) (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, "some.domain.com/range", nil)
+		},
+	}
+	sut := New(requesterMock, liveReq, WithBackfill(backfill))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	finderMock.EXPECT().LastDate(mock.Anything, "EUR").Return(day(time.Now()).AddDate(0, 0, -7), true, nil).Once()
+	requesterMock.EXPECT().Process(mock.MatchedBy(func(r *http.Request) bool {
+		return r.URL.Path == "some.domain.com/range"
+	})).Return(request.Descriptor{ID: "holiday", StatusCode: http.StatusNotFound, ErrorKind: request.ErrorHTTPStatus},
+		errors.New("status code 404")).Once()
+	requesterMock.EXPECT().Process(liveReq).Return(newDescriptor("live"), nil).Once()
+
+	// when
+	output, _ := sut.Start(ctx, 1, time.Minute)
+
+	// then
+	assert.Equal(t, "live", (<-output).ID)
+}
+
+func newDate(date string) time.Time {
+	t, _ := time.Parse(time.DateOnly, date)
+	return t
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidInterval = errors.New("invalid interval")
+)
+
+type Stats struct {
+	Ticks           uint64
+	Successes       uint64
+	Failures        uint64
+	LastError       error
+	LastSuccess     time.Time
+	Paused          bool
+	Interval        time.Duration
+	CurrentInterval time.Duration
+}
+
+type Control struct {
+	trigger     chan struct{}
+	reconfigure chan struct{}
+
+	mtx   sync.Mutex
+	stats Stats
+}
+
+func newControl(interval time.Duration) *Control {
+	return &Control{
+		trigger:     make(chan struct{}, 1),
+		reconfigure: make(chan struct{}, 1),
+		stats: Stats{
+			Interval:        interval,
+			CurrentInterval: interval,
+		},
+	}
+}
+
+func (c *Control) Pause() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stats.Paused = true
+}
+
+func (c *Control) Resume() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stats.Paused = false
+}
+
+func (c *Control) TriggerNow() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Control) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidInterval, interval)
+	}
+	c.mtx.Lock()
+	c.stats.Interval = interval
+	c.mtx.Unlock()
+
+	select {
+	case c.reconfigure <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *Control) Stats() Stats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stats
+}
+
+func (c *Control) paused() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stats.Paused
+}
+
+func (c *Control) interval() time.Duration {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stats.Interval
+}
+
+func (c *Control) setCurrentInterval(interval time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stats.CurrentInterval = interval
+}
+
+func (c *Control) record(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stats.Ticks++
+	if err != nil {
+		c.stats.Failures++
+		c.stats.LastError = err
+		return
+	}
+	c.stats.Successes++
+	c.stats.LastSuccess = time.Now()
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/monitor/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldTriggerUpdateOnDemandEvenWhenPaused(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	sut := New(requesterMock, req)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil)
+	output, control := sut.Start(ctx, 1, time.Hour)
+	<-output
+
+	// when
+	control.Pause()
+	control.TriggerNow()
+
+	// then
+	<-output
+	assert.Eventually(t, func() bool {
+		return control.Stats().Successes == 2
+	}, time.Second, 10*time.Millisecond)
+	stats := control.Stats()
+	assert.True(t, stats.Paused)
+	assert.Equal(t, uint64(2), stats.Ticks)
+	assert.NotZero(t, stats.LastSuccess)
+}
+
+func TestShouldSkipTicksWhilePaused(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	sut := New(requesterMock, req)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil)
+	output, control := sut.Start(ctx, 1, 20*time.Millisecond)
+	<-output
+
+	// when
+	control.Pause()
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// then
+	var extra int
+	for range output {
+		extra++
+	}
+	assert.LessOrEqual(t, extra, 1)
+}
+
+func TestShouldApplyNewInterval(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	sut := New(requesterMock, req)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil)
+	output, control := sut.Start(ctx, 1, time.Hour)
+	<-output
+
+	// when
+	err := control.SetInterval(10 * time.Millisecond)
+
+	// then
+	assert.NoError(t, err)
+	select {
+	case <-output:
+	case <-time.After(time.Second):
+		t.Fatal("no update after interval change")
+	}
+	assert.Equal(t, 10*time.Millisecond, control.Stats().Interval)
+}
+
+func TestShouldRejectNonPositiveInterval(t *testing.T) {
+	// given
+	control := newControl(time.Minute)
+
+	// when
+	err := control.SetInterval(0)
+
+	// then
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+	assert.Equal(t, time.Minute, control.Stats().Interval)
+}
+
+func TestShouldCountFailures(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	sut := New(requesterMock, req)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failure := errors.New("failure")
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), failure)
+
+	// when
+	output, control := sut.Start(ctx, 1, time.Minute)
+	for range output {
+	}
+
+	// then
+	stats := control.Stats()
+	assert.Equal(t, uint64(1), stats.Ticks)
+	assert.Equal(t, uint64(1), stats.Failures)
+	assert.Zero(t, stats.Successes)
+	assert.ErrorIs(t, stats.LastError, failure)
+}
@@ -17,46 +17,117 @@ type Requester interface {
 type Monitor struct {
 	requester Requester
 	request   *http.Request
+	options   *options
 }
 
-func New(requester Requester, request *http.Request) Monitor {
+type options struct {
+	backfill *Backfill
+	jitter   float64
+	adaptive *Adaptive
+}
+
+func defaultOptions() *options {
+	return &options{}
+}
+
+type Option func(*options)
+
+func WithBackfill(backfill Backfill) Option {
+	return func(o *options) {
+		o.backfill = &backfill
+	}
+}
+
+func WithJitter(fraction float64) Option {
+	return func(o *options) {
+		o.jitter = fraction
+	}
+}
+
+func WithAdaptiveInterval(adaptive Adaptive) Option {
+	return func(o *options) {
+		o.adaptive = &adaptive
+	}
+}
+
+func New(requester Requester, request *http.Request, opts ...Option) Monitor {
+	cfg := defaultOptions()
+	for _, o := range opts {
+		o(cfg)
+	}
 	return Monitor{
 		requester: requester,
 		request:   request,
+		options:   cfg,
 	}
 }
 
-func (m Monitor) Start(ctx context.Context, requestsNumber uint, interval time.Duration) <-chan request.Descriptor {
+func (m Monitor) Start(ctx context.Context, requestsNumber uint, interval time.Duration) (<-chan request.Descriptor, *Control) {
 	output := make(chan request.Descriptor)
+	control := newControl(interval)
 	go func() {
 		defer close(output)
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
 
-		m.update(requestsNumber, output)
+		if m.options.backfill != nil {
+			m.backfill(ctx, *m.options.backfill, output)
+		}
+
+		pacer := newPacer(interval, m.options.jitter, m.options.adaptive)
+		m.tick(control, pacer, requestsNumber, output)
+
+		timer := time.NewTimer(pacer.next())
+		defer timer.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				m.update(requestsNumber, output)
+			case <-timer.C:
+				if !control.paused() {
+					m.tick(control, pacer, requestsNumber, output)
+				}
+				timer.Reset(pacer.next())
+			case <-control.trigger:
+				m.tick(control, pacer, requestsNumber, output)
+			case <-control.reconfigure:
+				pacer.reset(control.interval())
+				control.setCurrentInterval(pacer.current)
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(pacer.next())
 			}
 		}
 	}()
-	return output
+	return output, control
+}
+
+func (m Monitor) tick(control *Control, pacer *pacer, number uint, output chan<- request.Descriptor) {
+	desc, err := m.update(number, output)
+	control.record(err)
+	pacer.observe(desc, err)
+	control.setCurrentInterval(pacer.current)
 }
 
-func (m Monitor) update(number uint, output chan<- request.Descriptor) {
-	for i := 0; i < int(number); i++ {
-		m.singleUpdate(output)
+func (m Monitor) update(number uint, output chan<- request.Descriptor) (request.Descriptor, error) {
+	switch {
+	case number == 0:
+		return request.Descriptor{}, nil
+	case number == 1:
+		return m.singleUpdate(output)
+	default:
+		desc, err := m.sample(number)
+		output <- desc
+		return desc, err
 	}
 }
 
-func (m Monitor) singleUpdate(output chan<- request.Descriptor) {
+func (m Monitor) singleUpdate(output chan<- request.Descriptor) (request.Descriptor, error) {
 	desc, err := m.requester.Process(m.request)
 	if err != nil {
 		slog.Error("monitor failed to process a request", "error", err)
+		desc.Error = err.Error()
 	}
 	output <- desc
+	return desc, err
 }
@@ -26,7 +26,7 @@ func TestShouldStopWhenContextIsCancelled(t *testing.T) {
 	requesterMock.EXPECT().Process(mock.Anything).Return(desc, nil)
 
 	// when
-	output := sut.Start(ctx, uint(requestsNumber), requestsInterval)
+	output, _ := sut.Start(ctx, uint(requestsNumber), requestsInterval)
 
 	// then
 	var descs []request.Descriptor
@@ -54,7 +54,7 @@ func TestShouldSendReceivedDescriptorToOutputChannel(t *testing.T) {
 	requesterMock.EXPECT().Process(mock.Anything).Return(desc, nil).Times(requestsNumber)
 
 	// when
-	output := sut.Start(ctx, uint(requestsNumber), requestsInterval)
+	output, _ := sut.Start(ctx, uint(requestsNumber), requestsInterval)
 
 	// then
 	var descs []request.Descriptor
@@ -67,7 +67,7 @@ func TestShouldSendReceivedDescriptorToOutputChannel(t *testing.T) {
 
 func TestShouldUpdateMonitorWithTimeInterval(t *testing.T) {
 	// given
-	expectedAllRequestsNumber := 4
+	expectedUpdatesNumber := 2
 	requestsNumber := 2
 	requestsInterval := 100 * time.Millisecond
 	requesterMock := mocks.NewRequester(t)
@@ -84,14 +84,17 @@ func TestShouldUpdateMonitorWithTimeInterval(t *testing.T) {
 	requesterMock.EXPECT().Process(mock.Anything).Return(desc, nil)
 
 	// when
-	output := sut.Start(ctx, uint(requestsNumber), requestsInterval)
+	output, _ := sut.Start(ctx, uint(requestsNumber), requestsInterval)
 
 	// then
 	var descs []request.Descriptor
 	for d := range output {
 		descs = append(descs, d)
 	}
-	assert.GreaterOrEqual(t, len(descs), expectedAllRequestsNumber)
+	assert.GreaterOrEqual(t, len(descs), expectedUpdatesNumber)
+	for _, d := range descs {
+		assert.Equal(t, requestsNumber, d.Sample.Probes)
+	}
 }
 
 func newDescriptor(ID string) request.Descriptor {
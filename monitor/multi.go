@@ -0,0 +1,199 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+var (
+	ErrInvalidTarget  = errors.New("invalid target")
+	ErrTargetExists   = errors.New("target already exists")
+	ErrTargetNotFound = errors.New("target not found")
+	ErrStopped        = errors.New("monitor stopped")
+)
+
+type Target struct {
+	Name           string
+	Requester      Requester
+	Request        *http.Request
+	RequestsNumber uint
+	Interval       time.Duration
+	Options        []Option
+}
+
+func (t Target) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidTarget)
+	}
+	if t.Requester == nil || t.Request == nil {
+		return fmt.Errorf("%w: target %s has no requester or request", ErrInvalidTarget, t.Name)
+	}
+	if t.Interval <= 0 {
+		return fmt.Errorf("%w: target %s has non-positive interval", ErrInvalidTarget, t.Name)
+	}
+	return nil
+}
+
+type runningTarget struct {
+	target  Target
+	cancel  context.CancelFunc
+	control *Control
+}
+
+type MultiMonitor struct {
+	pool chan struct{}
+
+	mtx     sync.Mutex
+	targets map[string]*runningTarget
+	ctx     context.Context
+	output  chan request.Descriptor
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+func NewMulti(concurrency uint) *MultiMonitor {
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	return &MultiMonitor{
+		pool:    make(chan struct{}, concurrency),
+		targets: make(map[string]*runningTarget),
+	}
+}
+
+func (m *MultiMonitor) Add(target Target) error {
+	if err := target.validate(); err != nil {
+		return err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.stopped {
+		return ErrStopped
+	}
+	if _, ok := m.targets[target.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrTargetExists, target.Name)
+	}
+	running := &runningTarget{
+		target: target,
+	}
+	m.targets[target.Name] = running
+	if m.ctx != nil {
+		m.run(running)
+	}
+	return nil
+}
+
+func (m *MultiMonitor) Remove(name string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	running, ok := m.targets[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTargetNotFound, name)
+	}
+	if running.cancel != nil {
+		running.cancel()
+	}
+	delete(m.targets, name)
+	return nil
+}
+
+func (m *MultiMonitor) Control(name string) (*Control, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	running, ok := m.targets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, name)
+	}
+	if running.control == nil {
+		return nil, fmt.Errorf("%w: target %s not started", ErrTargetNotFound, name)
+	}
+	return running.control, nil
+}
+
+func (m *MultiMonitor) Targets() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	names := make([]string, 0, len(m.targets))
+	for name := range m.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *MultiMonitor) Start(ctx context.Context) <-chan request.Descriptor {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.output != nil {
+		return m.output
+	}
+
+	m.ctx = ctx
+	m.output = make(chan request.Descriptor)
+	for _, running := range m.targets {
+		m.run(running)
+	}
+
+	go func() {
+		<-ctx.Done()
+		m.mtx.Lock()
+		m.stopped = true
+		m.mtx.Unlock()
+		m.wg.Wait()
+		close(m.output)
+	}()
+	return m.output
+}
+
+// run must be called with mtx held.
+func (m *MultiMonitor) run(running *runningTarget) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	running.cancel = cancel
+
+	target := running.target
+	requester := poolRequester{
+		requester: target.Requester,
+		pool:      m.pool,
+	}
+	mon := New(requester, target.Request.WithContext(ctx), target.Options...)
+	descs, control := mon.Start(ctx, target.RequestsNumber, target.Interval)
+	running.control = control
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for desc := range descs {
+			desc.Target = target.Name
+			select {
+			case m.output <- desc:
+			case <-ctx.Done():
+				// keep draining so the target monitor can finish its tick
+			}
+		}
+	}()
+}
+
+type poolRequester struct {
+	requester Requester
+	pool      chan struct{}
+}
+
+func (r poolRequester) Process(req *http.Request) (request.Descriptor, error) {
+	select {
+	case r.pool <- struct{}{}:
+	case <-req.Context().Done():
+		return request.Descriptor{}, req.Context().Err()
+	}
+	defer func() {
+		<-r.pool
+	}()
+	return r.requester.Process(req)
+}
@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/monitor/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldTagDescriptorsWithTargetName(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	sut := NewMulti(2)
+	assert.NoError(t, sut.Add(newTarget("eur", requesterMock)))
+	assert.NoError(t, sut.Add(newTarget("usd", requesterMock)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil)
+
+	// when
+	output := sut.Start(ctx)
+
+	// then
+	targets := []string{(<-output).Target, (<-output).Target}
+	assert.ElementsMatch(t, []string{"eur", "usd"}, targets)
+}
+
+func TestShouldRejectDuplicatedTarget(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	sut := NewMulti(1)
+	assert.NoError(t, sut.Add(newTarget("eur", requesterMock)))
+
+	// when
+	err := sut.Add(newTarget("eur", requesterMock))
+
+	// then
+	assert.ErrorIs(t, err, ErrTargetExists)
+}
+
+func TestShouldReturnErrorWhenRemovingUnknownTarget(t *testing.T) {
+	// given
+	sut := NewMulti(1)
+
+	// when
+	err := sut.Remove("eur")
+
+	// then
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+func TestShouldAddAndRemoveTargetsAtRuntime(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	sut := NewMulti(1)
+	assert.NoError(t, sut.Add(newTarget("eur", requesterMock)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requesterMock.EXPECT().Process(mock.Anything).Return(newDescriptor("1"), nil)
+	output := sut.Start(ctx)
+	assert.Equal(t, "eur", (<-output).Target)
+
+	// when
+	assert.NoError(t, sut.Add(newTarget("usd", requesterMock)))
+	added := <-output
+	assert.NoError(t, sut.Remove("eur"))
+
+	// then
+	assert.Equal(t, "usd", added.Target)
+	assert.Equal(t, []string{"usd"}, sut.Targets())
+	cancel()
+	for range output {
+	}
+	assert.ErrorIs(t, sut.Add(newTarget("chf", requesterMock)), ErrStopped)
+}
+
+func TestShouldLimitConcurrentRequests(t *testing.T) {
+	// given
+	concurrency := 2
+	requesterMock := mocks.NewRequester(t)
+	sut := NewMulti(uint(concurrency))
+	names := []string{"eur", "usd", "chf", "gbp", "jpy"}
+	for _, name := range names {
+		assert.NoError(t, sut.Add(newTarget(name, requesterMock)))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mtx       sync.Mutex
+		inFlight  int32
+		maxFlight int32
+	)
+	requesterMock.EXPECT().Process(mock.Anything).RunAndReturn(func(r *http.Request) (request.Descriptor, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		mtx.Lock()
+		if current > maxFlight {
+			maxFlight = current
+		}
+		mtx.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		return newDescriptor("1"), nil
+	})
+
+	// when
+	output := sut.Start(ctx)
+
+	// then
+	for range names {
+		<-output
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.LessOrEqual(t, maxFlight, int32(concurrency))
+}
+
+func TestShouldStopWithoutWaitingForConsumer(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	sut := NewMulti(1)
+	assert.NoError(t, sut.Add(newTarget("eur", requesterMock)))
+	ctx, cancel := context.WithCancel(context.Background())
+	processed := make(chan struct{})
+
+	requesterMock.EXPECT().Process(mock.Anything).RunAndReturn(func(r *http.Request) (request.Descriptor, error) {
+		close(processed)
+		return newDescriptor("1"), nil
+	}).Once()
+	sut.Start(ctx)
+	<-processed
+
+	// when
+	cancel()
+
+	// then
+	stopped := make(chan struct{})
+	go func() {
+		sut.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("target blocked on sending to a consumer which is not reading")
+	}
+}
+
+func TestShouldNotWaitForPoolSlotAfterCancellation(t *testing.T) {
+	// given
+	requesterMock := mocks.NewRequester(t)
+	sut := poolRequester{
+		requester: requesterMock,
+		pool:      make(chan struct{}, 1),
+	}
+	sut.pool <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "some.domain.com", nil)
+
+	// when
+	_, err := sut.Process(req)
+
+	// then
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func newTarget(name string, requester Requester) Target {
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	return Target{
+		Name:           name,
+		Requester:      requester,
+		Request:        req,
+		RequestsNumber: 1,
+		Interval:       time.Minute,
+	}
+}
@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+const (
+	defaultAdaptiveFactor = 2.0
+	defaultAdaptiveSpread = 4
+)
+
+// Adaptive bounds the polling interval. Unset Min and Max default to the base
+// interval divided by Factor and multiplied by four respectively.
+type Adaptive struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+type pacer struct {
+	jitter   float64
+	adaptive *Adaptive
+	random   func() float64
+
+	base    time.Duration
+	current time.Duration
+	last    request.Currency
+	seen    bool
+}
+
+func newPacer(interval time.Duration, jitter float64, adaptive *Adaptive) *pacer {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	p := &pacer{
+		jitter:   jitter,
+		adaptive: adaptive,
+		random:   rand.Float64,
+	}
+	p.reset(interval)
+	return p
+}
+
+func (p *pacer) reset(interval time.Duration) {
+	p.base = interval
+	p.current = p.bound(interval)
+}
+
+func (p *pacer) observe(desc request.Descriptor, err error) {
+	if p.adaptive == nil {
+		return
+	}
+	factor := p.factor()
+	changed := err == nil && (!p.seen || !samePayload(p.last, desc.Payload))
+	if err == nil {
+		p.last = desc.Payload
+		p.seen = true
+	}
+	if changed {
+		p.current = p.bound(time.Duration(float64(p.current) / factor))
+		return
+	}
+	p.current = p.bound(time.Duration(float64(p.current) * factor))
+}
+
+func (p *pacer) next() time.Duration {
+	if p.jitter == 0 {
+		return p.current
+	}
+	spread := p.jitter * (2*p.random() - 1)
+	next := time.Duration(float64(p.current) * (1 + spread))
+	if next <= 0 {
+		return time.Nanosecond
+	}
+	return next
+}
+
+func (p *pacer) bound(interval time.Duration) time.Duration {
+	if p.adaptive == nil {
+		return interval
+	}
+	lower := p.adaptive.Min
+	if lower <= 0 {
+		lower = time.Duration(float64(p.base) / p.factor())
+	}
+	upper := p.adaptive.Max
+	if upper <= 0 {
+		upper = p.base * defaultAdaptiveSpread
+	}
+	if interval < lower {
+		return lower
+	}
+	if interval > upper {
+		return upper
+	}
+	return interval
+}
+
+func (p *pacer) factor() float64 {
+	if p.adaptive.Factor <= 1 {
+		return defaultAdaptiveFactor
+	}
+	return p.adaptive.Factor
+}
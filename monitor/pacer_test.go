@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldKeepIntervalWithoutJitterAndAdaptation(t *testing.T) {
+	// given
+	sut := newPacer(time.Minute, 0, nil)
+
+	// when
+	sut.observe(newDescriptorWithRate("1", 4.5), nil)
+	sut.observe(newDescriptorWithRate("2", 4.5), errors.New("failure"))
+
+	// then
+	assert.Equal(t, time.Minute, sut.next())
+}
+
+func TestShouldSpreadIntervalWithinJitterBounds(t *testing.T) {
+	// given
+	sut := newPacer(time.Minute, 0.1, nil)
+
+	// when
+	sut.random = func() float64 { return 0 }
+	lowest := sut.next()
+	sut.random = func() float64 { return 1 }
+	highest := sut.next()
+
+	// then
+	assert.Equal(t, 54*time.Second, lowest)
+	assert.Equal(t, 66*time.Second, highest)
+}
+
+func TestShouldLengthenIntervalWhenNothingChangesOrErrorsOccur(t *testing.T) {
+	// given
+	sut := newPacer(time.Minute, 0, &Adaptive{Min: 30 * time.Second, Max: 5 * time.Minute, Factor: 2})
+	sut.observe(newDescriptorWithRate("1", 4.5), nil)
+	assert.Equal(t, 30*time.Second, sut.next())
+
+	// when
+	sut.observe(newDescriptorWithRate("2", 4.5), nil)
+	sut.observe(newDescriptorWithRate("3", 4.5), errors.New("too many requests"))
+	sut.observe(newDescriptorWithRate("4", 4.5), nil)
+	sut.observe(newDescriptorWithRate("5", 4.5), nil)
+
+	// then
+	assert.Equal(t, 5*time.Minute, sut.next())
+}
+
+func TestShouldShortenIntervalWhenNewDataAppears(t *testing.T) {
+	// given
+	sut := newPacer(4*time.Minute, 0, &Adaptive{Min: 30 * time.Second, Max: 5 * time.Minute, Factor: 2})
+	sut.observe(newDescriptorWithRate("1", 4.5), nil)
+	assert.Equal(t, 2*time.Minute, sut.next())
+
+	// when
+	sut.observe(newDescriptorWithRate("2", 4.6), nil)
+
+	// then
+	assert.Equal(t, time.Minute, sut.next())
+}
+
+func TestShouldBoundAdaptiveIntervalByDefault(t *testing.T) {
+	// given
+	sut := newPacer(time.Minute, 0, &Adaptive{Factor: 2})
+
+	// when
+	for i := 0; i < 10; i++ {
+		sut.observe(newDescriptorWithRate("1", 4.5), errors.New("failure"))
+	}
+	longest := sut.next()
+	for i := 0; i < 10; i++ {
+		sut.observe(newDescriptorWithRate("1", 4.5+float64(i)), nil)
+	}
+	shortest := sut.next()
+
+	// then
+	assert.Equal(t, 4*time.Minute, longest)
+	assert.Equal(t, 30*time.Second, shortest)
+}
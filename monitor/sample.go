@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"golang.org/x/exp/slog"
+)
+
+type probe struct {
+	desc request.Descriptor
+	err  error
+}
+
+func (m Monitor) sample(number uint) (request.Descriptor, error) {
+	probes := make([]probe, number)
+	var wg sync.WaitGroup
+	wg.Add(len(probes))
+	for i := range probes {
+		go func(i int) {
+			defer wg.Done()
+			desc, err := m.requester.Process(m.request)
+			probes[i] = probe{
+				desc: desc,
+				err:  err,
+			}
+		}(i)
+	}
+	wg.Wait()
+	return consolidate(probes)
+}
+
+type payloadGroup struct {
+	first int
+	count int
+}
+
+func consolidate(probes []probe) (request.Descriptor, error) {
+	var (
+		groups    []payloadGroup
+		latencies []time.Duration
+		errs      []error
+		succeeded int
+		attempts  int
+	)
+	for i, p := range probes {
+		attempts += p.desc.Attempts
+		if p.desc.Duration > 0 {
+			latencies = append(latencies, p.desc.Duration)
+		}
+		if p.err != nil {
+			slog.Error("monitor probe failed", "id", p.desc.ID, "error", p.err)
+			errs = append(errs, p.err)
+			continue
+		}
+		succeeded++
+		matched := false
+		for g := range groups {
+			if samePayload(probes[groups[g].first].desc.Payload, p.desc.Payload) {
+				groups[g].count++
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			groups = append(groups, payloadGroup{first: i, count: 1})
+		}
+	}
+
+	result := probes[0].desc
+	agreeing := 0
+	for _, g := range groups {
+		if g.count > agreeing {
+			agreeing = g.count
+			result = probes[g.first].desc
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	result.Attempts = attempts
+	result.Sample = request.Sample{
+		Probes:       len(probes),
+		Succeeded:    succeeded,
+		Agreeing:     agreeing,
+		Quorum:       agreeing*2 > len(probes),
+		Disagreement: len(groups) > 1,
+		LatencyP50:   percentile(latencies, 50),
+		LatencyP90:   percentile(latencies, 90),
+		LatencyP99:   percentile(latencies, 99),
+	}
+	if result.Sample.Disagreement {
+		slog.Warn("monitor probes disagree", "url", result.URL, "variants", len(groups), "agreeing", agreeing)
+	}
+	if succeeded == 0 {
+		err := errors.Join(errs...)
+		result.Error = err.Error()
+		return result, err
+	}
+	return result, nil
+}
+
+func samePayload(a, b request.Currency) bool {
+	if a.Name != b.Name || len(a.Rates) != len(b.Rates) {
+		return false
+	}
+	for i := range a.Rates {
+		if !a.Rates[i].Date.Equal(b.Rates[i].Date) || a.Rates[i].Value != b.Rates[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// percentile expects sorted input and uses the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/monitor/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldEmitSingleConsolidatedDescriptorForAllProbes(t *testing.T) {
+	// given
+	requestsNumber := 5
+	requesterMock := mocks.NewRequester(t)
+	req, _ := http.NewRequest(http.MethodGet, "some.domain.com", nil)
+	sut := New(requesterMock, req)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	desc := newDescriptor("1")
+	desc.Duration = time.Millisecond
+	requesterMock.EXPECT().Process(mock.Anything).Return(desc, nil).Times(requestsNumber)
+
+	// when
+	output, _ := sut.Start(ctx, uint(requestsNumber), time.Minute)
+
+	// then
+	var descs []request.Descriptor
+	for d := range output {
+		descs = append(descs, d)
+	}
+	assert.Len(t, descs, 1)
+	assert.Equal(t, request.Sample{
+		Probes:     requestsNumber,
+		Succeeded:  requestsNumber,
+		Agreeing:   requestsNumber,
+		Quorum:     true,
+		LatencyP50: time.Millisecond,
+		LatencyP90: time.Millisecond,
+		LatencyP99: time.Millisecond,
+	}, descs[0].Sample)
+}
+
+func TestShouldPickMajorityPayloadAndFlagDisagreement(t *testing.T) {
+	// given
+	majority := newDescriptorWithRate("1", 4.5)
+	minority := newDescriptorWithRate("2", 4.6)
+	probes := []probe{
+		{desc: minority},
+		{desc: majority},
+		{desc: newDescriptorWithRate("3", 4.5)},
+	}
+
+	// when
+	result, err := consolidate(probes)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, majority.ID, result.ID)
+	assert.Equal(t, 2, result.Sample.Agreeing)
+	assert.True(t, result.Sample.Quorum)
+	assert.True(t, result.Sample.Disagreement)
+}
+
+func TestShouldReportNoQuorumWhenMostProbesFail(t *testing.T) {
+	// given
+	probes := []probe{
+		{desc: newDescriptorWithRate("1", 4.5)},
+		{desc: newDescriptor("2"), err: errors.New("failure")},
+		{desc: newDescriptor("3"), err: errors.New("failure")},
+	}
+
+	// when
+	result, err := consolidate(probes)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "1", result.ID)
+	assert.Equal(t, 1, result.Sample.Succeeded)
+	assert.False(t, result.Sample.Quorum)
+	assert.False(t, result.Sample.Disagreement)
+}
+
+func TestShouldReturnErrorWhenAllProbesFail(t *testing.T) {
+	// given
+	first := newDescriptor("1")
+	first.ErrorKind = request.ErrorHTTPStatus
+	first.Attempts = 1
+	second := newDescriptor("2")
+	second.ErrorKind = request.ErrorTransport
+	second.Attempts = 1
+	probes := []probe{
+		{desc: first, err: errors.New("failure 1")},
+		{desc: second, err: errors.New("failure 2")},
+	}
+
+	// when
+	result, err := consolidate(probes)
+
+	// then
+	assert.Error(t, err)
+	assert.Equal(t, "1", result.ID)
+	assert.Zero(t, result.Sample.Agreeing)
+	assert.Equal(t, request.ErrorHTTPStatus, result.ErrorKind)
+	assert.Equal(t, 2, result.Attempts)
+	assert.True(t, result.Failed())
+}
+
+func TestShouldComputeLatencyPercentiles(t *testing.T) {
+	// given
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	// when
+	p50 := percentile(latencies, 50)
+	p90 := percentile(latencies, 90)
+	p99 := percentile(latencies, 99)
+
+	// then
+	assert.Equal(t, 50*time.Millisecond, p50)
+	assert.Equal(t, 90*time.Millisecond, p90)
+	assert.Equal(t, 99*time.Millisecond, p99)
+}
+
+func newDescriptorWithRate(ID string, value float64) request.Descriptor {
+	desc := newDescriptor(ID)
+	desc.Payload = request.Currency{
+		Name: "EUR",
+		Rates: []request.Rate{
+			{
+				Date:  time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC),
+				Value: value,
+			},
+		},
+	}
+	return desc
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/koenno/currency-price-monitor/alert"
+)
+
+type Filter func(alert.Event) bool
+
+func MinSeverity(severity alert.Severity) Filter {
+	return func(e alert.Event) bool {
+		return e.Severity.Rank() >= severity.Rank()
+	}
+}
+
+func Names(names ...string) Filter {
+	allowed := make(map[string]bool, len(names))
+	for _, n := range names {
+		allowed[n] = true
+	}
+	return func(e alert.Event) bool {
+		return allowed[e.Name]
+	}
+}
+
+func Currencies(currencies ...string) Filter {
+	allowed := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		allowed[c] = true
+	}
+	return func(e alert.Event) bool {
+		return allowed[e.Currency]
+	}
+}
+
+type route struct {
+	sink    alert.Sink
+	filters []Filter
+}
+
+func (r route) matches(e alert.Event) bool {
+	for _, f := range r.filters {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+type Dispatcher struct {
+	routes []route
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+func (d *Dispatcher) Route(sink alert.Sink, filters ...Filter) {
+	d.routes = append(d.routes, route{
+		sink:    sink,
+		filters: filters,
+	})
+}
+
+func (d *Dispatcher) Notify(ctx context.Context, events []alert.Event) error {
+	var errs []error
+	for _, r := range d.routes {
+		var matched []alert.Event
+		for _, e := range events {
+			if r.matches(e) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if err := r.sink.Notify(ctx, matched); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
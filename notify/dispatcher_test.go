@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldRouteEventsMatchingFilters(t *testing.T) {
+	// given
+	criticalSink := mocks.NewSink(t)
+	thresholdSink := mocks.NewSink(t)
+	sut := NewDispatcher()
+	sut.Route(criticalSink, MinSeverity(alert.SeverityCritical))
+	sut.Route(thresholdSink, Names("threshold"), Currencies("EUR"))
+	warning := newEvent("threshold", alert.SeverityWarning)
+	critical := newEvent("change", alert.SeverityCritical)
+
+	criticalSink.EXPECT().Notify(mock.Anything, []alert.Event{critical}).Return(nil).Once()
+	thresholdSink.EXPECT().Notify(mock.Anything, []alert.Event{warning}).Return(nil).Once()
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{warning, critical})
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldDeliverToRemainingSinksWhenOneFails(t *testing.T) {
+	// given
+	failingSink := mocks.NewSink(t)
+	workingSink := mocks.NewSink(t)
+	sut := NewDispatcher()
+	sut.Route(failingSink)
+	sut.Route(workingSink)
+	events := []alert.Event{newEvent("threshold", alert.SeverityWarning)}
+
+	failingSink.EXPECT().Notify(mock.Anything, events).Return(errors.New("failure")).Once()
+	workingSink.EXPECT().Notify(mock.Anything, events).Return(nil).Once()
+
+	// when
+	err := sut.Notify(context.Background(), events)
+
+	// then
+	assert.Error(t, err)
+}
@@ -0,0 +1,341 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	defaultTextTemplate = `{{len .Alerts}} currency alert(s)
+{{range .Alerts}}
+[{{.Status}}] {{.Currency}} {{.Name}}
+{{.Message}}
+rate {{.Rate}} on {{.Date}}
+{{end}}`
+
+	defaultHTMLTemplate = `<html><body>
+<p>{{len .Alerts}} currency alert(s)</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Status</th><th>Currency</th><th>Alert</th><th>Date</th><th>Rate</th><th>Message</th></tr>
+{{range .Alerts}}<tr><td>{{.Status}}</td><td>{{.Currency}}</td><td>{{.Name}}</td><td>{{.Date}}</td><td>{{.Rate}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+</body></html>`
+)
+
+const (
+	defaultSMTPPort    = 587
+	defaultSMTPTimeout = 30 * time.Second
+)
+
+var (
+	ErrInvalidEmailConfig = errors.New("invalid email configuration")
+)
+
+type SMTPConfig struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	To        []string
+	StartTLS  bool
+	TLSConfig *tls.Config
+	// Timeout bounds a whole delivery, from dialing the server to quitting.
+	Timeout time.Duration
+}
+
+type EmailData struct {
+	Alerts []EmailAlert
+}
+
+type EmailAlert struct {
+	Status   string
+	Name     string
+	Currency string
+	Severity alert.Severity
+	Rate     float64
+	Date     string
+	Message  string
+}
+
+type emailOptions struct {
+	window time.Duration
+	text   string
+	html   string
+}
+
+type EmailOption func(*emailOptions)
+
+func WithDigestWindow(window time.Duration) EmailOption {
+	return func(o *emailOptions) {
+		o.window = window
+	}
+}
+
+func WithTextTemplate(tmpl string) EmailOption {
+	return func(o *emailOptions) {
+		o.text = tmpl
+	}
+}
+
+func WithHTMLTemplate(tmpl string) EmailOption {
+	return func(o *emailOptions) {
+		o.html = tmpl
+	}
+}
+
+type Email struct {
+	cfg    SMTPConfig
+	window time.Duration
+	text   *texttemplate.Template
+	html   *htmltemplate.Template
+
+	mtx     sync.Mutex
+	pending []alert.Event
+	timer   *time.Timer
+}
+
+func NewEmail(cfg SMTPConfig, opts ...EmailOption) (*Email, error) {
+	o := emailOptions{
+		text: defaultTextTemplate,
+		html: defaultHTMLTemplate,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("%w: host, sender and recipients are required", ErrInvalidEmailConfig)
+	}
+	recipients := make([]string, 0, len(cfg.To))
+	for _, to := range cfg.To {
+		to = strings.TrimSpace(to)
+		if to == "" {
+			return nil, fmt.Errorf("%w: empty recipient", ErrInvalidEmailConfig)
+		}
+		recipients = append(recipients, to)
+	}
+	cfg.To = recipients
+	if cfg.Port == 0 {
+		cfg.Port = defaultSMTPPort
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+	text, err := texttemplate.New("text").Parse(o.text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: text template: %v", ErrInvalidEmailConfig, err)
+	}
+	html, err := htmltemplate.New("html").Parse(o.html)
+	if err != nil {
+		return nil, fmt.Errorf("%w: html template: %v", ErrInvalidEmailConfig, err)
+	}
+	return &Email{
+		cfg:    cfg,
+		window: o.window,
+		text:   text,
+		html:   html,
+	}, nil
+}
+
+func (e *Email) Notify(ctx context.Context, events []alert.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if e.window <= 0 {
+		return e.send(ctx, events)
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.pending = append(e.pending, events...)
+	e.schedule()
+	return nil
+}
+
+// Flush sends the pending digest right away. Alerts of a digest that could
+// not be sent are kept for the next one.
+func (e *Email) Flush(ctx context.Context) error {
+	e.mtx.Lock()
+	events := e.pending
+	e.pending = nil
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.mtx.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	if err := e.send(ctx, events); err != nil {
+		e.mtx.Lock()
+		e.pending = append(events, e.pending...)
+		e.schedule()
+		e.mtx.Unlock()
+		return err
+	}
+	return nil
+}
+
+// schedule must be called with mtx held.
+func (e *Email) schedule() {
+	if e.timer != nil || e.window <= 0 {
+		return
+	}
+	e.timer = time.AfterFunc(e.window, func() {
+		if err := e.Flush(context.Background()); err != nil {
+			slog.Error("failed to send alert digest", "error", err)
+		}
+	})
+}
+
+func (e *Email) send(ctx context.Context, events []alert.Event) error {
+	msg, err := e.message(events)
+	if err != nil {
+		return fmt.Errorf("%w: unable to compose email: %v", ErrDelivery, err)
+	}
+	if err := e.deliver(ctx, msg); err != nil {
+		return fmt.Errorf("%w: smtp %s: %v", ErrDelivery, e.cfg.Host, err)
+	}
+	return nil
+}
+
+func (e *Email) deliver(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// unblock the conversation as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.cfg.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		tlsConfig := e.cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: e.cfg.Host}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		auth := smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (e *Email) message(events []alert.Event) ([]byte, error) {
+	data := EmailData{}
+	for _, ev := range events {
+		status := strings.ToUpper(string(ev.Severity))
+		if ev.Resolved {
+			status = "RESOLVED"
+		}
+		data.Alerts = append(data.Alerts, EmailAlert{
+			Status:   status,
+			Name:     ev.Name,
+			Currency: ev.Currency,
+			Severity: ev.Severity,
+			Rate:     ev.Rate.Value,
+			Date:     ev.Rate.Date.Format(time.DateOnly),
+			Message:  ev.Message,
+		})
+	}
+
+	var text, html bytes.Buffer
+	if err := e.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := e.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{contentType: "text/plain; charset=utf-8", content: text.Bytes()},
+		{contentType: "text/html; charset=utf-8", content: html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		w.Write(part.content)
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", summary(events)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,347 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldSendMultipartEmail(t *testing.T) {
+	// given
+	server := newFakeSMTPServer(t, nil)
+	sut, err := NewEmail(server.config())
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.NoError(t, err)
+	messages := server.messages()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "monitor@example.com", messages[0].from)
+	assert.Equal(t, []string{"finance@example.com"}, messages[0].to)
+	assert.Contains(t, messages[0].data, "Subject: [WARNING] EUR threshold")
+	assert.Contains(t, messages[0].data, "multipart/alternative")
+	assert.Contains(t, messages[0].data, "text/plain")
+	assert.Contains(t, messages[0].data, "<td>4.75</td>")
+	assert.Contains(t, messages[0].data, "rate 4.75 on 2023-10-03")
+}
+
+func TestShouldUpgradeConnectionWithSTARTTLS(t *testing.T) {
+	// given
+	server := newFakeSMTPServer(t, newSelfSignedCertificate(t))
+	cfg := server.config()
+	cfg.StartTLS = true
+	cfg.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	sut, err := NewEmail(cfg)
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.NoError(t, err)
+	messages := server.messages()
+	assert.Len(t, messages, 1)
+	assert.True(t, messages[0].tls)
+}
+
+func TestShouldFailWhenSTARTTLSIsRequiredButNotOffered(t *testing.T) {
+	// given
+	server := newFakeSMTPServer(t, nil)
+	cfg := server.config()
+	cfg.StartTLS = true
+	sut, err := NewEmail(cfg)
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.ErrorIs(t, err, ErrDelivery)
+	assert.Empty(t, server.messages())
+}
+
+func TestShouldBatchAlertsIntoDigest(t *testing.T) {
+	// given
+	server := newFakeSMTPServer(t, nil)
+	sut, err := NewEmail(server.config(), WithDigestWindow(50*time.Millisecond))
+	assert.NoError(t, err)
+
+	// when
+	errFirst := sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+	errSecond := sut.Notify(context.Background(), []alert.Event{newEvent("change", alert.SeverityCritical)})
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Empty(t, server.messages())
+	assert.Eventually(t, func() bool {
+		return len(server.messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, server.messages()[0].data, "Subject: 2 currency alerts")
+}
+
+func TestShouldKeepAlertsOfDigestThatCouldNotBeSent(t *testing.T) {
+	// given
+	server := startFakeSMTPServer(t, &fakeSMTPServer{failures: 1})
+	sut, err := NewEmail(server.config(), WithDigestWindow(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)}))
+
+	// when
+	errFailed := sut.Flush(context.Background())
+	assert.NoError(t, sut.Notify(context.Background(), []alert.Event{newEvent("change", alert.SeverityCritical)}))
+	errSent := sut.Flush(context.Background())
+
+	// then
+	assert.ErrorIs(t, errFailed, ErrDelivery)
+	assert.NoError(t, errSent)
+	messages := server.messages()
+	assert.Len(t, messages, 1)
+	assert.Contains(t, messages[0].data, "Subject: 2 currency alerts")
+}
+
+func TestShouldGiveUpWhenServerDoesNotAnswer(t *testing.T) {
+	// given
+	cfg := newSilentSMTPServer(t)
+	cfg.Timeout = 50 * time.Millisecond
+	sut, err := NewEmail(cfg)
+	assert.NoError(t, err)
+
+	// when
+	started := time.Now()
+	err = sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.ErrorIs(t, err, ErrDelivery)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestShouldStopDeliveryWhenContextIsCancelled(t *testing.T) {
+	// given
+	sut, err := NewEmail(newSilentSMTPServer(t))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// when
+	started := time.Now()
+	err = sut.Notify(ctx, []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.ErrorIs(t, err, ErrDelivery)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestShouldTrimRecipients(t *testing.T) {
+	// given
+	server := newFakeSMTPServer(t, nil)
+	cfg := server.config()
+	cfg.To = []string{"finance@example.com", " treasury@example.com "}
+	sut, err := NewEmail(cfg)
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.NoError(t, err)
+	messages := server.messages()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, []string{"finance@example.com", "treasury@example.com"}, messages[0].to)
+	assert.Contains(t, messages[0].data, "To: finance@example.com, treasury@example.com\r\n")
+}
+
+func TestShouldRejectIncompleteConfig(t *testing.T) {
+	// when
+	_, err := NewEmail(SMTPConfig{Host: "localhost"})
+
+	// then
+	assert.ErrorIs(t, err, ErrInvalidEmailConfig)
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+}
+
+type fakeSMTPServer struct {
+	listener net.Listener
+	cert     *tls.Certificate
+
+	mtx      sync.Mutex
+	received []smtpMessage
+	// failures is the number of messages rejected before accepting any.
+	failures int
+	silent   bool
+}
+
+func newFakeSMTPServer(t *testing.T, cert *tls.Certificate) *fakeSMTPServer {
+	return startFakeSMTPServer(t, &fakeSMTPServer{cert: cert})
+}
+
+// newSilentSMTPServer accepts connections but never answers them.
+func newSilentSMTPServer(t *testing.T) SMTPConfig {
+	return startFakeSMTPServer(t, &fakeSMTPServer{silent: true}).config()
+}
+
+func startFakeSMTPServer(t *testing.T, s *fakeSMTPServer) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s.listener = listener
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SMTPConfig{
+		Host: host,
+		Port: p,
+		From: "monitor@example.com",
+		To:   []string{"finance@example.com"},
+	}
+}
+
+func (s *fakeSMTPServer) messages() []smtpMessage {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]smtpMessage(nil), s.received...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer func() {
+		conn.Close()
+	}()
+	if s.silent {
+		io.Copy(io.Discard, conn)
+		return
+	}
+	var (
+		msg    smtpMessage
+		reader = bufio.NewReader(conn)
+		reply  = func(line string) {
+			conn.Write([]byte(line + "\r\n"))
+		}
+	)
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			if s.cert != nil && !msg.tls {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			} else {
+				reply("250 localhost")
+			}
+		case command == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			msg.tls = true
+		case strings.HasPrefix(command, "MAIL FROM:") && s.fail():
+			reply("451 try again later")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<> ")
+			reply("250 ok")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			address := strings.TrimSpace(line)[len("RCPT TO:"):]
+			msg.to = append(msg.to, strings.TrimSuffix(strings.TrimPrefix(address, "<"), ">"))
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.data = data.String()
+			s.mtx.Lock()
+			s.received = append(s.received, msg)
+			s.mtx.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) fail() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.failures == 0 {
+		return false
+	}
+	s.failures--
+	return true
+}
+
+func newSelfSignedCertificate(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
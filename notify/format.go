@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+)
+
+var severityColors = map[alert.Severity]string{
+	alert.SeverityInfo:     "#2eb886",
+	alert.SeverityWarning:  "#daa038",
+	alert.SeverityCritical: "#a30200",
+}
+
+const resolvedColor = "#439fe0"
+
+type eventPayload struct {
+	Key      string             `json:"key"`
+	Name     string             `json:"name"`
+	Currency string             `json:"currency"`
+	Date     string             `json:"date"`
+	Rate     float64            `json:"rate"`
+	Severity alert.Severity     `json:"severity"`
+	Message  string             `json:"message"`
+	Time     time.Time          `json:"time"`
+	Resolved bool               `json:"resolved"`
+	Labels   map[string]string  `json:"labels,omitempty"`
+	Values   map[string]float64 `json:"values,omitempty"`
+}
+
+type jsonPayload struct {
+	Alerts []eventPayload `json:"alerts"`
+}
+
+type slackAttachment struct {
+	Color    string `json:"color"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Fallback string `json:"fallback"`
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	ActivityTitle string      `json:"activityTitle"`
+	Text          string      `json:"text"`
+	Facts         []teamsFact `json:"facts"`
+}
+
+type teamsPayload struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	ThemeColor string         `json:"themeColor"`
+	Title      string         `json:"title"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+func encode(format Format, events []alert.Event) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		return json.Marshal(slackMessage(events))
+	case FormatTeams:
+		return json.Marshal(teamsMessage(events))
+	case FormatJSON:
+		payload := jsonPayload{}
+		for _, e := range events {
+			payload.Alerts = append(payload.Alerts, toPayload(e))
+		}
+		return json.Marshal(payload)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func toPayload(e alert.Event) eventPayload {
+	return eventPayload{
+		Key:      e.Key,
+		Name:     e.Name,
+		Currency: e.Currency,
+		Date:     e.Rate.Date.Format(time.DateOnly),
+		Rate:     e.Rate.Value,
+		Severity: e.Severity,
+		Message:  e.Message,
+		Time:     e.Time,
+		Resolved: e.Resolved,
+		Labels:   e.Labels,
+		Values:   e.Values,
+	}
+}
+
+func summary(events []alert.Event) string {
+	if len(events) == 1 {
+		return title(events[0])
+	}
+	return fmt.Sprintf("%d currency alerts", len(events))
+}
+
+func title(e alert.Event) string {
+	status := strings.ToUpper(string(e.Severity))
+	if e.Resolved {
+		status = "RESOLVED"
+	}
+	return fmt.Sprintf("[%s] %s %s", status, e.Currency, e.Name)
+}
+
+func color(e alert.Event) string {
+	if e.Resolved {
+		return resolvedColor
+	}
+	return severityColors[e.Severity]
+}
+
+func slackMessage(events []alert.Event) slackPayload {
+	payload := slackPayload{
+		Text: summary(events),
+	}
+	for _, e := range events {
+		payload.Attachments = append(payload.Attachments, slackAttachment{
+			Color:    color(e),
+			Title:    title(e),
+			Text:     fmt.Sprintf("%s\nrate %v on %s", e.Message, e.Rate.Value, e.Rate.Date.Format(time.DateOnly)),
+			Fallback: e.Message,
+		})
+	}
+	return payload
+}
+
+func teamsMessage(events []alert.Event) teamsPayload {
+	payload := teamsPayload{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		Summary:    summary(events),
+		ThemeColor: strings.TrimPrefix(color(mostSevere(events)), "#"),
+		Title:      summary(events),
+	}
+	for _, e := range events {
+		payload.Sections = append(payload.Sections, teamsSection{
+			ActivityTitle: title(e),
+			Text:          e.Message,
+			Facts: []teamsFact{
+				{Name: "Currency", Value: e.Currency},
+				{Name: "Rate", Value: fmt.Sprint(e.Rate.Value)},
+				{Name: "Date", Value: e.Rate.Date.Format(time.DateOnly)},
+				{Name: "Severity", Value: string(e.Severity)},
+			},
+		})
+	}
+	return payload
+}
+
+func mostSevere(events []alert.Event) alert.Event {
+	result := events[0]
+	for _, e := range events[1:] {
+		if e.Severity.Rank() > result.Severity.Rank() {
+			result = e
+		}
+	}
+	return result
+}
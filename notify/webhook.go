@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+
+	defaultAttempts = 3
+	defaultBackoff  = time.Second
+)
+
+var (
+	ErrDelivery = errors.New("notification delivery failed")
+
+	httpClient = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+)
+
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatSlack Format = "slack"
+	FormatTeams Format = "teams"
+)
+
+type webhookOptions struct {
+	format   Format
+	headers  http.Header
+	secret   []byte
+	attempts int
+	backoff  time.Duration
+	client   *http.Client
+}
+
+type WebhookOption func(*webhookOptions)
+
+func WithFormat(format Format) WebhookOption {
+	return func(o *webhookOptions) {
+		o.format = format
+	}
+}
+
+func WithHeader(key, value string) WebhookOption {
+	return func(o *webhookOptions) {
+		o.headers.Add(key, value)
+	}
+}
+
+func WithHMAC(secret []byte) WebhookOption {
+	return func(o *webhookOptions) {
+		o.secret = secret
+	}
+}
+
+func WithRetry(attempts int, backoff time.Duration) WebhookOption {
+	return func(o *webhookOptions) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(o *webhookOptions) {
+		o.client = client
+	}
+}
+
+type Webhook struct {
+	url     string
+	options webhookOptions
+}
+
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	cfg := webhookOptions{
+		format:   FormatJSON,
+		headers:  make(http.Header),
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+		client:   httpClient,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.attempts < 1 {
+		cfg.attempts = 1
+	}
+	return &Webhook{
+		url:     url,
+		options: cfg,
+	}
+}
+
+func NewSlack(url string, opts ...WebhookOption) *Webhook {
+	return NewWebhook(url, append(opts, WithFormat(FormatSlack))...)
+}
+
+func NewTeams(url string, opts ...WebhookOption) *Webhook {
+	return NewWebhook(url, append(opts, WithFormat(FormatTeams))...)
+}
+
+func (w *Webhook) Notify(ctx context.Context, events []alert.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := encode(w.options.format, events)
+	if err != nil {
+		return fmt.Errorf("%w: unable to encode payload: %v", ErrDelivery, err)
+	}
+
+	backoff := w.options.backoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := w.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= w.options.attempts {
+			return fmt.Errorf("%w: %s after %d attempt(s): %v", ErrDelivery, w.url, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s: %v", ErrDelivery, w.url, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range w.options.headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "currency-price-monitor")
+	if len(w.options.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.options.secret, body))
+	}
+
+	resp, err := w.options.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status code %d; body %s", resp.StatusCode, string(respBody))
+	default:
+		return false, fmt.Errorf("status code %d; body %s", resp.StatusCode, string(respBody))
+	}
+}
+
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldPostSignedJSONWithCustomHeaders(t *testing.T) {
+	// given
+	secret := []byte("secret")
+	var (
+		body      []byte
+		signature string
+		token     string
+	)
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		token = r.Header.Get("Authorization")
+	}))
+	defer fakeServer.Close()
+	sut := NewWebhook(fakeServer.URL, WithHMAC(secret), WithHeader("Authorization", "Bearer token"))
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", token)
+	assert.Equal(t, Sign(secret, body), signature)
+	var payload jsonPayload
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Len(t, payload.Alerts, 1)
+	assert.Equal(t, "EUR", payload.Alerts[0].Currency)
+	assert.Equal(t, "2023-10-03", payload.Alerts[0].Date)
+	assert.Equal(t, 4.75, payload.Alerts[0].Rate)
+}
+
+func TestShouldRetryOnServerErrors(t *testing.T) {
+	// given
+	var calls int32
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer fakeServer.Close()
+	sut := NewWebhook(fakeServer.URL, WithRetry(3, time.Millisecond))
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestShouldNotRetryOnClientErrors(t *testing.T) {
+	// given
+	var calls int32
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer fakeServer.Close()
+	sut := NewWebhook(fakeServer.URL, WithRetry(3, time.Millisecond))
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{newEvent("threshold", alert.SeverityWarning)})
+
+	// then
+	assert.ErrorIs(t, err, ErrDelivery)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestShouldSendSlackMessage(t *testing.T) {
+	// given
+	var payload slackPayload
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer fakeServer.Close()
+	sut := NewSlack(fakeServer.URL)
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{
+		newEvent("threshold", alert.SeverityWarning),
+		newEvent("change", alert.SeverityCritical),
+	})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "2 currency alerts", payload.Text)
+	assert.Len(t, payload.Attachments, 2)
+	assert.Equal(t, "[CRITICAL] EUR change", payload.Attachments[1].Title)
+	assert.Equal(t, severityColors[alert.SeverityCritical], payload.Attachments[1].Color)
+}
+
+func TestShouldSendTeamsMessageCard(t *testing.T) {
+	// given
+	var payload teamsPayload
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer fakeServer.Close()
+	sut := NewTeams(fakeServer.URL)
+	event := newEvent("threshold", alert.SeverityInfo)
+	event.Resolved = true
+
+	// when
+	err := sut.Notify(context.Background(), []alert.Event{event})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "MessageCard", payload.Type)
+	assert.Equal(t, "[RESOLVED] EUR threshold", payload.Title)
+	assert.Equal(t, "439fe0", payload.ThemeColor)
+	assert.Len(t, payload.Sections, 1)
+	assert.Contains(t, payload.Sections[0].Facts, teamsFact{Name: "Rate", Value: "4.75"})
+}
+
+func newEvent(name string, severity alert.Severity) alert.Event {
+	date, _ := time.Parse(time.DateOnly, "2023-10-03")
+	return alert.Event{
+		Key:      name + "/EUR",
+		Name:     name,
+		Currency: "EUR",
+		Rate: request.Rate{
+			Date:  date,
+			Value: 4.75,
+		},
+		Severity: severity,
+		Message:  "EUR rate 4.75 moved above band [4.5, 4.7]",
+		Time:     time.Now(),
+	}
+}
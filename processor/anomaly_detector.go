@@ -0,0 +1,278 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	anomalyAlertName = "anomaly"
+
+	defaultAnomalyWindow   = 30
+	defaultZScoreThreshold = 3.0
+	defaultMADThreshold    = 3.5
+	madConsistencyConstant = 0.6745
+	minAnomalyWarmUp       = 2
+)
+
+type AnomalyMethod string
+
+const (
+	AnomalyZScore AnomalyMethod = "zscore"
+	AnomalyMAD    AnomalyMethod = "mad"
+)
+
+type AnomalyConfig struct {
+	Method    AnomalyMethod
+	Window    int
+	Threshold float64
+	WarmUp    int
+}
+
+func (c AnomalyConfig) withDefaults() AnomalyConfig {
+	if c.Method == "" {
+		c.Method = AnomalyZScore
+	}
+	if c.Window <= 0 {
+		c.Window = defaultAnomalyWindow
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = defaultZScoreThreshold
+		if c.Method == AnomalyMAD {
+			c.Threshold = defaultMADThreshold
+		}
+	}
+	if c.WarmUp <= 0 || c.WarmUp > c.Window {
+		c.WarmUp = c.Window
+	}
+	if c.WarmUp < minAnomalyWarmUp {
+		c.WarmUp = minAnomalyWarmUp
+	}
+	return c
+}
+
+type WindowStats struct {
+	Size   int
+	Mean   float64
+	StdDev float64
+	Median float64
+	MAD    float64
+}
+
+type anomalyWindow struct {
+	values []float64
+	last   time.Time
+	// scored is the date of the latest rate scored, possibly before a restart.
+	scored time.Time
+}
+
+type anomalyState struct {
+	LastDate time.Time `json:"last_date"`
+}
+
+type anomalyOptions struct {
+	configs map[string]AnomalyConfig
+	store   state.Store
+}
+
+type AnomalyOption func(*anomalyOptions)
+
+func WithCurrencyAnomalyConfig(currency string, cfg AnomalyConfig) AnomalyOption {
+	return func(o *anomalyOptions) {
+		o.configs[currency] = cfg.withDefaults()
+	}
+}
+
+// WithAnomalyStore keeps the date of the latest scored rate, so rates replayed
+// after a restart only refill the window.
+func WithAnomalyStore(store state.Store) AnomalyOption {
+	return func(o *anomalyOptions) {
+		o.store = store
+	}
+}
+
+type AnomalyDetector struct {
+	sink    alert.Sink
+	cfg     AnomalyConfig
+	options anomalyOptions
+
+	mtx     sync.Mutex
+	windows map[string]*anomalyWindow
+}
+
+func NewAnomalyDetector(sink alert.Sink, cfg AnomalyConfig, opts ...AnomalyOption) *AnomalyDetector {
+	options := anomalyOptions{
+		configs: make(map[string]AnomalyConfig),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &AnomalyDetector{
+		sink:    sink,
+		cfg:     cfg.withDefaults(),
+		options: options,
+		windows: make(map[string]*anomalyWindow),
+	}
+}
+
+func (d *AnomalyDetector) Process(ctx context.Context, desc request.Descriptor) error {
+	events, err := d.evaluate(desc.Payload)
+	if len(events) == 0 {
+		return err
+	}
+	return errors.Join(err, d.sink.Notify(ctx, events))
+}
+
+func (d *AnomalyDetector) config(currency string) AnomalyConfig {
+	if cfg, ok := d.options.configs[currency]; ok {
+		return cfg
+	}
+	return d.cfg
+}
+
+func (d *AnomalyDetector) evaluate(payload request.Currency) ([]alert.Event, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	cfg := d.config(payload.Name)
+	window, err := d.window(payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	scored := window.scored
+	// without a record of scored rates the first descriptor only fills the
+	// window, so a fresh start does not alert on anomalies long gone
+	warmUp := scored.IsZero()
+
+	var events []alert.Event
+	for _, rate := range sortedRates(payload.Rates) {
+		if len(window.values) > 0 && !rate.Date.After(window.last) {
+			continue
+		}
+		if !warmUp && rate.Date.After(scored) && len(window.values) >= cfg.WarmUp {
+			score, stats, ok := anomalyScore(cfg.Method, window.values, rate.Value)
+			if ok && math.Abs(score) >= cfg.Threshold {
+				events = append(events, newAnomalyEvent(payload.Name, rate, cfg, score, stats))
+			}
+		}
+		window.values = append(window.values, rate.Value)
+		if len(window.values) > cfg.Window {
+			window.values = window.values[len(window.values)-cfg.Window:]
+		}
+		window.last = rate.Date
+	}
+	if !window.last.After(scored) {
+		return events, nil
+	}
+	window.scored = window.last
+	if d.options.store == nil {
+		return events, nil
+	}
+	return events, d.options.store.Save(anomalyKey(payload.Name), anomalyState{LastDate: window.scored})
+}
+
+// window must be called with mtx held.
+func (d *AnomalyDetector) window(currency string) (*anomalyWindow, error) {
+	if window, ok := d.windows[currency]; ok {
+		return window, nil
+	}
+	window := &anomalyWindow{}
+	if d.options.store != nil {
+		var s anomalyState
+		err := d.options.store.Load(anomalyKey(currency), &s)
+		if err != nil && !errors.Is(err, state.ErrNotFound) {
+			return nil, err
+		}
+		window.scored = s.LastDate
+	}
+	d.windows[currency] = window
+	return window, nil
+}
+
+func anomalyKey(currency string) string {
+	return fmt.Sprintf("%s/%s", anomalyAlertName, currency)
+}
+
+func anomalyScore(method AnomalyMethod, values []float64, value float64) (float64, WindowStats, bool) {
+	stats := windowStats(values)
+	switch method {
+	case AnomalyMAD:
+		if stats.MAD == 0 {
+			return 0, stats, false
+		}
+		return madConsistencyConstant * (value - stats.Median) / stats.MAD, stats, true
+	default:
+		if stats.StdDev == 0 {
+			return 0, stats, false
+		}
+		return (value - stats.Mean) / stats.StdDev, stats, true
+	}
+}
+
+func windowStats(values []float64) WindowStats {
+	stats := WindowStats{
+		Size: len(values),
+	}
+	for _, v := range values {
+		stats.Mean += v
+	}
+	stats.Mean /= float64(len(values))
+	for _, v := range values {
+		stats.StdDev += (v - stats.Mean) * (v - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(stats.StdDev / float64(len(values)))
+
+	stats.Median = median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - stats.Median)
+	}
+	stats.MAD = median(deviations)
+	return stats
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func newAnomalyEvent(currency string, rate request.Rate, cfg AnomalyConfig, score float64, stats WindowStats) alert.Event {
+	return alert.Event{
+		Key:      fmt.Sprintf("%s/%s", anomalyAlertName, currency),
+		Name:     anomalyAlertName,
+		Currency: currency,
+		Rate:     rate,
+		Severity: alert.SeverityWarning,
+		Message: fmt.Sprintf("%s rate %v on %s is anomalous (%s score %.2f, threshold %.2f)",
+			currency, rate.Value, rate.Date.Format(time.DateOnly), cfg.Method, score, cfg.Threshold),
+		Time: time.Now(),
+		Labels: map[string]string{
+			"method": string(cfg.Method),
+		},
+		Values: map[string]float64{
+			"value":       rate.Value,
+			"score":       score,
+			"threshold":   cfg.Threshold,
+			"window_size": float64(stats.Size),
+			"mean":        stats.Mean,
+			"stddev":      stats.StdDev,
+			"median":      stats.Median,
+			"mad":         stats.MAD,
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	statemocks "github.com/koenno/currency-price-monitor/state/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldFlagRateWithHighZScore(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewAnomalyDetector(sinkMock, AnomalyConfig{
+		Method:    AnomalyZScore,
+		Window:    5,
+		Threshold: 3,
+	})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Once()
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 4.48),
+		newRate("2023-10-05", 4.51),
+		newRate("2023-10-06", 4.49),
+	)))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-09", 4.80)))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, newDate("2023-10-09"), notified[0].Rate.Date)
+	assert.Greater(t, notified[0].Values["score"], 3.0)
+	assert.InDelta(t, 4.50, notified[0].Values["mean"], 1e-9)
+	assert.Equal(t, 5.0, notified[0].Values["window_size"])
+}
+
+func TestShouldNotScoreDuringWarmUp(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewAnomalyDetector(sinkMock, AnomalyConfig{
+		Window: 10,
+	})
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-02", 4.50))))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 9.00),
+	))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldOnlyFillWindowFromFirstDescriptor(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewAnomalyDetector(sinkMock, AnomalyConfig{
+		Method:    AnomalyZScore,
+		Window:    5,
+		Threshold: 3,
+	})
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 4.48),
+		newRate("2023-10-05", 4.51),
+		newRate("2023-10-06", 4.49),
+		newRate("2023-10-09", 4.80),
+	))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldUseCurrencySpecificMADConfig(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewAnomalyDetector(sinkMock, AnomalyConfig{Window: 100},
+		WithCurrencyAnomalyConfig("USD", AnomalyConfig{
+			Method: AnomalyMAD,
+			Window: 5,
+		}))
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Once()
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("USD",
+		newRate("2023-10-02", 4.00),
+		newRate("2023-10-03", 4.02),
+		newRate("2023-10-04", 3.98),
+		newRate("2023-10-05", 4.01),
+		newRate("2023-10-06", 3.99),
+	)))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("USD", newRate("2023-10-09", 4.10)))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, "mad", notified[0].Labels["method"])
+	assert.InDelta(t, 4.00, notified[0].Values["median"], 1e-9)
+	assert.InDelta(t, 0.01, notified[0].Values["mad"], 1e-9)
+}
+
+func TestShouldNotScoreRatesReplayedAfterRestart(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	storeMock := statemocks.NewStore(t)
+	sut := NewAnomalyDetector(sinkMock, AnomalyConfig{
+		Method:    AnomalyZScore,
+		Window:    5,
+		Threshold: 3,
+	}, WithAnomalyStore(storeMock))
+
+	storeMock.EXPECT().Load("anomaly/EUR", mock.Anything).RunAndReturn(func(key string, v any) error {
+		*v.(*anomalyState) = anomalyState{LastDate: newDate("2023-10-09")}
+		return nil
+	}).Once()
+	storeMock.EXPECT().Save("anomaly/EUR", anomalyState{LastDate: newDate("2023-10-10")}).Return(nil).Once()
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 4.48),
+		newRate("2023-10-05", 4.51),
+		newRate("2023-10-06", 4.49),
+		newRate("2023-10-09", 4.80),
+		newRate("2023-10-10", 4.55),
+	))
+
+	// then
+	assert.NoError(t, err)
+}
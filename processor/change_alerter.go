@@ -0,0 +1,202 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	changeAlertName = "change"
+
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+type ChangeThreshold struct {
+	Currency     string
+	Absolute     float64
+	Percentage   float64
+	LookbackDays uint
+}
+
+type changeOptions struct {
+	store state.Store
+}
+
+type ChangeOption func(*changeOptions)
+
+func WithChangeStore(store state.Store) ChangeOption {
+	return func(o *changeOptions) {
+		o.store = store
+	}
+}
+
+type ChangeAlerter struct {
+	sink      alert.Sink
+	threshold ChangeThreshold
+	options   changeOptions
+
+	mtx       sync.Mutex
+	histories map[string][]request.Rate
+}
+
+func NewChangeAlerter(sink alert.Sink, threshold ChangeThreshold, opts ...ChangeOption) *ChangeAlerter {
+	cfg := changeOptions{}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &ChangeAlerter{
+		sink:      sink,
+		threshold: threshold,
+		options:   cfg,
+		histories: make(map[string][]request.Rate),
+	}
+}
+
+func (a *ChangeAlerter) Process(ctx context.Context, desc request.Descriptor) error {
+	if a.threshold.Currency != "" && desc.Payload.Name != a.threshold.Currency {
+		return nil
+	}
+	events, err := a.evaluate(desc.Payload)
+	if len(events) == 0 {
+		return err
+	}
+	return errors.Join(err, a.sink.Notify(ctx, events))
+}
+
+func (a *ChangeAlerter) evaluate(payload request.Currency) ([]alert.Event, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	history, err := a.history(payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	known := len(history)
+	// the first descriptor only fills the history, so a fresh start does not
+	// alert on changes long gone
+	warmUp := known == 0
+	var events []alert.Event
+	for _, rate := range sortedRates(payload.Rates) {
+		if len(history) > 0 && !rate.Date.After(history[len(history)-1].Date) {
+			continue
+		}
+		if warmUp {
+			history = append(history, rate)
+			continue
+		}
+		if len(history) > 0 {
+			previous := history[len(history)-1]
+			if event, ok := a.compare(payload.Name, "previous", previous, rate); ok {
+				events = append(events, event)
+			}
+		}
+		if a.threshold.LookbackDays > 0 {
+			if past, ok := rateAt(history, rate.Date.AddDate(0, 0, -int(a.threshold.LookbackDays))); ok {
+				window := fmt.Sprintf("%dd", a.threshold.LookbackDays)
+				if event, ok := a.compare(payload.Name, window, past, rate); ok {
+					events = append(events, event)
+				}
+			}
+		}
+		history = append(history, rate)
+	}
+	added := len(history) > known
+	history = a.prune(history)
+	a.histories[payload.Name] = history
+	if a.options.store == nil || !added {
+		return events, nil
+	}
+	return events, a.options.store.Save(changeKey(payload.Name), history)
+}
+
+// history must be called with mtx held.
+func (a *ChangeAlerter) history(currency string) ([]request.Rate, error) {
+	if history, ok := a.histories[currency]; ok || a.options.store == nil {
+		return history, nil
+	}
+	var history []request.Rate
+	err := a.options.store.Load(changeKey(currency), &history)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return nil, err
+	}
+	a.histories[currency] = history
+	return history, nil
+}
+
+func changeKey(currency string) string {
+	return fmt.Sprintf("%s/%s", changeAlertName, currency)
+}
+
+func (a *ChangeAlerter) compare(currency, window string, from, to request.Rate) (alert.Event, bool) {
+	change := to.Value - from.Value
+	var changePct float64
+	if from.Value != 0 {
+		changePct = change / from.Value * 100
+	}
+	absExceeded := a.threshold.Absolute > 0 && math.Abs(change) >= a.threshold.Absolute
+	pctExceeded := a.threshold.Percentage > 0 && math.Abs(changePct) >= a.threshold.Percentage
+	if !absExceeded && !pctExceeded {
+		return alert.Event{}, false
+	}
+
+	direction := DirectionUp
+	if change < 0 {
+		direction = DirectionDown
+	}
+	return alert.Event{
+		Key:      fmt.Sprintf("%s/%s/%s", changeAlertName, currency, window),
+		Name:     changeAlertName,
+		Currency: currency,
+		Rate:     to,
+		Severity: alert.SeverityWarning,
+		Message: fmt.Sprintf("%s rate moved %s by %.4f (%.2f%%) from %v on %s to %v on %s",
+			currency, direction, math.Abs(change), math.Abs(changePct),
+			from.Value, from.Date.Format(time.DateOnly), to.Value, to.Date.Format(time.DateOnly)),
+		Time: time.Now(),
+		Labels: map[string]string{
+			"direction": direction,
+			"window":    window,
+		},
+		Values: map[string]float64{
+			"value":      to.Value,
+			"previous":   from.Value,
+			"change":     change,
+			"change_pct": changePct,
+		},
+	}, true
+}
+
+func (a *ChangeAlerter) prune(history []request.Rate) []request.Rate {
+	if len(history) < 2 {
+		return history
+	}
+	horizon := history[len(history)-1].Date.AddDate(0, 0, -int(a.threshold.LookbackDays)-7)
+	idx := sort.Search(len(history), func(i int) bool {
+		return !history[i].Date.Before(horizon)
+	})
+	if idx >= len(history)-1 {
+		idx = len(history) - 1
+	}
+	return history[idx:]
+}
+
+// rateAt returns the latest rate published on or before the given date.
+func rateAt(history []request.Rate, date time.Time) (request.Rate, bool) {
+	idx := sort.Search(len(history), func(i int) bool {
+		return history[i].Date.After(date)
+	})
+	if idx == 0 {
+		return request.Rate{}, false
+	}
+	return history[idx-1], true
+}
@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldNotifyAboutPercentageChangeBetweenConsecutiveRates(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewChangeAlerter(sinkMock, ChangeThreshold{
+		Percentage: 1,
+	})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	})
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-02", 4.50))))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 4.40),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, DirectionDown, notified[0].Labels["direction"])
+	assert.Equal(t, "previous", notified[0].Labels["window"])
+	assert.InDelta(t, -0.12, notified[0].Values["change"], 1e-9)
+	assert.InDelta(t, -2.6549, notified[0].Values["change_pct"], 1e-4)
+}
+
+func TestShouldNotifyAboutAbsoluteChangeAgainstLookbackRate(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewChangeAlerter(sinkMock, ChangeThreshold{
+		Absolute:     0.05,
+		LookbackDays: 3,
+	})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	})
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.52),
+		newRate("2023-10-04", 4.54),
+	)))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-05", 4.56)))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, DirectionUp, notified[0].Labels["direction"])
+	assert.Equal(t, "3d", notified[0].Labels["window"])
+	assert.Equal(t, 4.50, notified[0].Values["previous"])
+}
+
+func TestShouldCompareOnlyAgainstAlreadySeenRates(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewChangeAlerter(sinkMock, ChangeThreshold{
+		Currency:   "EUR",
+		Percentage: 1,
+	})
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-02", 4.50))))
+	first := newRatesDescriptor("EUR", newRate("2023-10-03", 4.60))
+
+	// when
+	errFirst := sut.Process(context.Background(), first)
+	errSecond := sut.Process(context.Background(), first)
+	errOther := sut.Process(context.Background(), newRatesDescriptor("USD", newRate("2023-10-03", 1)))
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errOther)
+}
+
+func TestShouldNotReplayChangesAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	threshold := ChangeThreshold{Percentage: 1}
+	desc := newRatesDescriptor("EUR", newRate("2023-10-02", 4.5), newRate("2023-10-03", 4.8))
+	firstSink := mocks.NewSink(t)
+	firstSink.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	first := NewChangeAlerter(firstSink, threshold, WithChangeStore(store))
+	assert.NoError(t, first.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.5))))
+	assert.NoError(t, first.Process(context.Background(), desc))
+
+	// when
+	secondSink := mocks.NewSink(t)
+	var notified []alert.Event
+	secondSink.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	})
+	second := NewChangeAlerter(secondSink, threshold, WithChangeStore(store))
+	err := second.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.5),
+		newRate("2023-10-03", 4.8),
+		newRate("2023-10-04", 4.5),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, DirectionDown, notified[0].Labels["direction"])
+}
+
+func TestShouldOnlyFillHistoryFromFirstDescriptor(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewChangeAlerter(sinkMock, ChangeThreshold{
+		Percentage:   1,
+		LookbackDays: 1,
+	})
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.90),
+		newRate("2023-10-04", 4.20),
+	))
+
+	// then
+	assert.NoError(t, err)
+}
@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/koenno/currency-price-monitor/csvrate"
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+type CSVWriter struct {
+	mtx    sync.Mutex
+	writer *csvrate.Writer
+}
+
+func NewCSVWriter(out io.Writer, format csvrate.Format) (*CSVWriter, error) {
+	writer, err := csvrate.NewWriter(out, format)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVWriter{
+		writer: writer,
+	}, nil
+}
+
+func (w *CSVWriter) Process(ctx context.Context, desc request.Descriptor) error {
+	if len(desc.Payload.Rates) == 0 {
+		return nil
+	}
+	desc.Payload.Rates = sortedRates(desc.Payload.Rates)
+	records := recordsOf(desc)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.writer.Write(records)
+}
@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/csvrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldStreamRatesAsCSV(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	format := csvrate.DefaultFormat()
+	format.Columns = []csvrate.Column{csvrate.ColumnDate, csvrate.ColumnCurrency, csvrate.ColumnValue}
+	sut, err := NewCSVWriter(out, format)
+	assert.NoError(t, err)
+
+	// when
+	errFirst := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-03", 4.61),
+		newRate("2023-10-02", 4.60),
+	))
+	errSecond := sut.Process(context.Background(), newRatesDescriptor("USD", newRate("2023-10-03", 4.35)))
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Equal(t, "date,currency,value\n2023-10-02,EUR,4.6\n2023-10-03,EUR,4.61\n2023-10-03,USD,4.35\n", out.String())
+}
+
+func TestShouldAppendOnlyNewRatesBehindDeduplicator(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	format := csvrate.DefaultFormat()
+	format.Columns = []csvrate.Column{csvrate.ColumnDate, csvrate.ColumnValue}
+	format.Delimiter = ';'
+	format.DecimalSeparator = ','
+	writer, err := NewCSVWriter(out, format)
+	assert.NoError(t, err)
+	sut, err := NewDeduplicator(writer)
+	assert.NoError(t, err)
+	history := newRatesDescriptor("EUR", newRate("2023-10-02", 4.60), newRate("2023-10-03", 4.61))
+
+	// when
+	errFirst := sut.Process(context.Background(), history)
+	errRepeated := sut.Process(context.Background(), history)
+	errNext := sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61), newRate("2023-10-04", 4.62)))
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errRepeated)
+	assert.NoError(t, errNext)
+	assert.Equal(t, "date;value\n2023-10-02;4,6\n2023-10-03;4,61\n2023-10-04;4,62\n", out.String())
+}
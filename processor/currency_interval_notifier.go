@@ -2,13 +2,19 @@ package processor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
 )
 
 const (
 	multiplier = 10000
+
+	intervalStatePrefix = "interval"
 )
 
 type ClosedInterval struct {
@@ -16,23 +22,84 @@ type ClosedInterval struct {
 	B float64
 }
 
+type intervalOptions struct {
+	store state.Store
+}
+
+type IntervalOption func(*intervalOptions)
+
+func WithIntervalStore(store state.Store) IntervalOption {
+	return func(o *intervalOptions) {
+		o.store = store
+	}
+}
+
+type intervalState struct {
+	LastNotified time.Time `json:"last_notified"`
+}
+
 type CurrencyIntervalWriter struct {
 	out      io.Writer
+	currency string
 	interval ClosedInterval
+	options  intervalOptions
 }
 
-func NewCurrencyIntervalNotifier(out io.Writer, interval ClosedInterval) CurrencyIntervalWriter {
+func NewCurrencyIntervalNotifier(out io.Writer, currency string, interval ClosedInterval, opts ...IntervalOption) CurrencyIntervalWriter {
+	cfg := intervalOptions{}
+	for _, o := range opts {
+		o(&cfg)
+	}
 	return CurrencyIntervalWriter{
 		out:      out,
+		currency: currency,
 		interval: interval,
+		options:  cfg,
 	}
 }
 
 func (n CurrencyIntervalWriter) Process(ctx context.Context, desc request.Descriptor) error {
+	if n.currency != "" && desc.Payload.Name != n.currency {
+		return nil
+	}
+	last, err := n.lastNotified(desc.Payload.Name)
+	if err != nil {
+		return err
+	}
+	notified := last
 	for i := 0; i < len(desc.Payload.Rates); i++ {
-		if desc.Payload.Rates[i].Value < n.interval.A || desc.Payload.Rates[i].Value > n.interval.B {
-			desc.Payload.Rates[i].WriteTo(n.out)
+		rate := desc.Payload.Rates[i]
+		if !rate.Date.After(last) {
+			continue
+		}
+		if rate.Value < n.interval.A || rate.Value > n.interval.B {
+			rate.WriteTo(n.out)
+			if rate.Date.After(notified) {
+				notified = rate.Date
+			}
 		}
 	}
-	return nil
+	if n.options.store == nil || !notified.After(last) {
+		return nil
+	}
+	return n.options.store.Save(intervalKey(desc.Payload.Name), intervalState{LastNotified: notified})
+}
+
+func (n CurrencyIntervalWriter) lastNotified(currency string) (time.Time, error) {
+	if n.options.store == nil {
+		return time.Time{}, nil
+	}
+	var s intervalState
+	err := n.options.store.Load(intervalKey(currency), &s)
+	if errors.Is(err, state.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.LastNotified, nil
+}
+
+func intervalKey(currency string) string {
+	return fmt.Sprintf("%s/%s", intervalStatePrefix, currency)
 }
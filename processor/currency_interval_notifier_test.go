@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldWriteRatesOutsideInterval(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut := NewCurrencyIntervalNotifier(out, "EUR", ClosedInterval{A: 4.5, B: 4.7})
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.6),
+		newRate("2023-10-03", 4.8),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), "4.8")
+}
+
+func TestShouldNotRewriteNotifiedRatesAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	interval := ClosedInterval{A: 4.5, B: 4.7}
+	desc := newRatesDescriptor("EUR", newRate("2023-10-02", 4.4), newRate("2023-10-03", 4.8))
+	first := NewCurrencyIntervalNotifier(&bytes.Buffer{}, "EUR", interval, WithIntervalStore(store))
+	assert.NoError(t, first.Process(context.Background(), desc))
+
+	// when
+	out := &bytes.Buffer{}
+	second := NewCurrencyIntervalNotifier(out, "EUR", interval, WithIntervalStore(store))
+	desc.Payload.Rates = append(desc.Payload.Rates, newRate("2023-10-04", 4.9))
+	err := second.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), "4.9")
+}
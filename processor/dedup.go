@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/scheduler"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	defaultRevisionWindowDays = 31
+)
+
+type dedupOptions struct {
+	store          state.Store
+	storeKey       string
+	revisionWindow uint
+}
+
+type DedupOption func(*dedupOptions)
+
+// WithDedupStore keeps the watermarks under key, so rates forwarded before a
+// restart are not forwarded again.
+func WithDedupStore(store state.Store, key string) DedupOption {
+	return func(o *dedupOptions) {
+		o.store = store
+		o.storeKey = key
+	}
+}
+
+func WithRevisionWindow(days uint) DedupOption {
+	return func(o *dedupOptions) {
+		o.revisionWindow = days
+	}
+}
+
+type Deduplicator struct {
+	next    scheduler.Processor
+	options dedupOptions
+
+	mtx        sync.Mutex
+	watermarks map[string]time.Time
+	seen       map[string]map[time.Time]float64
+}
+
+func NewDeduplicator(next scheduler.Processor, opts ...DedupOption) (*Deduplicator, error) {
+	cfg := dedupOptions{
+		revisionWindow: defaultRevisionWindowDays,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	d := &Deduplicator{
+		next:       next,
+		options:    cfg,
+		watermarks: make(map[string]time.Time),
+		seen:       make(map[string]map[time.Time]float64),
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Process forwards rates not seen before. The rates are marked as seen only
+// after the next processor accepted them, so a failed attempt is repeated
+// with the next descriptor.
+func (d *Deduplicator) Process(ctx context.Context, desc request.Descriptor) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	fresh := d.filter(desc.Payload)
+	if len(fresh) == 0 {
+		return d.commit(desc.Payload)
+	}
+	forwarded := desc
+	forwarded.Payload.Rates = fresh
+	if err := d.next.Process(ctx, forwarded); err != nil {
+		return err
+	}
+	return d.commit(desc.Payload)
+}
+
+func (d *Deduplicator) Watermark(currency string) (time.Time, bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	watermark, ok := d.watermarks[currency]
+	return watermark, ok
+}
+
+// filter must be called with mtx held.
+func (d *Deduplicator) filter(payload request.Currency) []request.Rate {
+	watermark := d.watermarks[payload.Name]
+	seen := d.seen[payload.Name]
+
+	var fresh []request.Rate
+	for _, rate := range payload.Rates {
+		date := rate.Date.UTC()
+		previous, known := seen[date]
+		switch {
+		case date.After(watermark):
+			if !known || previous != rate.Value {
+				fresh = append(fresh, rate)
+			}
+		case known && previous != rate.Value:
+			fresh = append(fresh, rate)
+		}
+	}
+	return fresh
+}
+
+// commit marks the rates of payload as seen; it must be called with mtx held.
+func (d *Deduplicator) commit(payload request.Currency) error {
+	watermark := d.watermarks[payload.Name]
+	seen, ok := d.seen[payload.Name]
+	if !ok {
+		seen = make(map[time.Time]float64)
+		d.seen[payload.Name] = seen
+	}
+
+	latest := watermark
+	for _, rate := range payload.Rates {
+		date := rate.Date.UTC()
+		seen[date] = rate.Value
+		if date.After(latest) {
+			latest = date
+		}
+	}
+
+	horizon := latest.AddDate(0, 0, -int(d.options.revisionWindow))
+	for date := range seen {
+		if date.Before(horizon) {
+			delete(seen, date)
+		}
+	}
+
+	if !latest.After(watermark) {
+		return nil
+	}
+	d.watermarks[payload.Name] = latest
+	return d.save()
+}
+
+func (d *Deduplicator) load() error {
+	if d.options.store == nil {
+		return nil
+	}
+	err := d.options.store.Load(d.options.storeKey, &d.watermarks)
+	if errors.Is(err, state.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load watermarks: %v", err)
+	}
+	return nil
+}
+
+// save must be called with mtx held.
+func (d *Deduplicator) save() error {
+	if d.options.store == nil {
+		return nil
+	}
+	if err := d.options.store.Save(d.options.storeKey, d.watermarks); err != nil {
+		return fmt.Errorf("unable to save watermarks: %v", err)
+	}
+	return nil
+}
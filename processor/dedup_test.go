@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/scheduler/mocks"
+	"github.com/koenno/currency-price-monitor/state"
+	statemocks "github.com/koenno/currency-price-monitor/state/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldForwardOnlyNewRates(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	sut, err := NewDeduplicator(procMock)
+	assert.NoError(t, err)
+	first := newRatesDescriptor("EUR", newRate("2023-10-02", 4.5), newRate("2023-10-03", 4.6))
+	second := newRatesDescriptor("EUR", newRate("2023-10-03", 4.6), newRate("2023-10-04", 4.7))
+
+	procMock.EXPECT().Process(mock.Anything, first).Return(nil).Once()
+	procMock.EXPECT().Process(mock.Anything, newRatesDescriptor("EUR", newRate("2023-10-04", 4.7))).Return(nil).Once()
+
+	// when
+	errFirst := sut.Process(context.Background(), first)
+	errSecond := sut.Process(context.Background(), second)
+	errThird := sut.Process(context.Background(), second)
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errThird)
+}
+
+func TestShouldForwardRevisedRates(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	sut, err := NewDeduplicator(procMock)
+	assert.NoError(t, err)
+	original := newRatesDescriptor("EUR", newRate("2023-10-02", 4.5), newRate("2023-10-03", 4.6))
+	revised := newRatesDescriptor("EUR", newRate("2023-10-02", 4.55), newRate("2023-10-03", 4.6))
+
+	procMock.EXPECT().Process(mock.Anything, original).Return(nil).Once()
+	procMock.EXPECT().Process(mock.Anything, newRatesDescriptor("EUR", newRate("2023-10-02", 4.55))).Return(nil).Once()
+
+	// when
+	errOriginal := sut.Process(context.Background(), original)
+	errRevised := sut.Process(context.Background(), revised)
+
+	// then
+	assert.NoError(t, errOriginal)
+	assert.NoError(t, errRevised)
+}
+
+func TestShouldTrackCurrenciesSeparately(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	sut, err := NewDeduplicator(procMock)
+	assert.NoError(t, err)
+	eur := newRatesDescriptor("EUR", newRate("2023-10-03", 4.6))
+	usd := newRatesDescriptor("USD", newRate("2023-10-03", 4.2))
+
+	procMock.EXPECT().Process(mock.Anything, eur).Return(nil).Once()
+	procMock.EXPECT().Process(mock.Anything, usd).Return(nil).Once()
+
+	// when
+	errEUR := sut.Process(context.Background(), eur)
+	errUSD := sut.Process(context.Background(), usd)
+
+	// then
+	assert.NoError(t, errEUR)
+	assert.NoError(t, errUSD)
+}
+
+func TestShouldRestoreWatermarkFromStore(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	procMock := mocks.NewProcessor(t)
+	before, err := NewDeduplicator(procMock, WithDedupStore(store, "dedup/test"))
+	assert.NoError(t, err)
+	history := newRatesDescriptor("EUR", newRate("2023-10-02", 4.5), newRate("2023-10-03", 4.6))
+	procMock.EXPECT().Process(mock.Anything, history).Return(nil).Once()
+	assert.NoError(t, before.Process(context.Background(), history))
+
+	// when
+	sut, err := NewDeduplicator(procMock, WithDedupStore(store, "dedup/test"))
+
+	// then
+	assert.NoError(t, err)
+	watermark, ok := sut.Watermark("EUR")
+	assert.True(t, ok)
+	assert.Equal(t, newDate("2023-10-03"), watermark)
+	assert.NoError(t, sut.Process(context.Background(), history))
+}
+
+func TestShouldForwardRatesAgainWhenNextProcessorFails(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	procMock := mocks.NewProcessor(t)
+	sut, err := NewDeduplicator(procMock, WithDedupStore(store, "dedup/test"))
+	assert.NoError(t, err)
+	desc := newRatesDescriptor("EUR", newRate("2023-10-03", 4.6))
+
+	procMock.EXPECT().Process(mock.Anything, desc).Return(errors.New("failure")).Once()
+	procMock.EXPECT().Process(mock.Anything, desc).Return(nil).Once()
+
+	// when
+	errFirst := sut.Process(context.Background(), desc)
+	_, committed := sut.Watermark("EUR")
+	errSecond := sut.Process(context.Background(), desc)
+
+	// then
+	assert.Error(t, errFirst)
+	assert.False(t, committed)
+	assert.NoError(t, errSecond)
+	var watermarks map[string]time.Time
+	assert.NoError(t, store.Load("dedup/test", &watermarks))
+	assert.Equal(t, newDate("2023-10-03"), watermarks["EUR"])
+}
+
+func TestShouldReportWatermarkThatCannotBeSaved(t *testing.T) {
+	// given
+	storeMock := statemocks.NewStore(t)
+	procMock := mocks.NewProcessor(t)
+	storeMock.EXPECT().Load("dedup/test", mock.Anything).Return(state.ErrNotFound).Once()
+	sut, err := NewDeduplicator(procMock, WithDedupStore(storeMock, "dedup/test"))
+	assert.NoError(t, err)
+	desc := newRatesDescriptor("EUR", newRate("2023-10-03", 4.6))
+
+	procMock.EXPECT().Process(mock.Anything, desc).Return(nil).Once()
+	storeMock.EXPECT().Save("dedup/test", mock.Anything).Return(errors.New("disk full")).Once()
+
+	// when
+	err = sut.Process(context.Background(), desc)
+
+	// then
+	assert.ErrorContains(t, err, "unable to save watermarks")
+}
+
+func newRatesDescriptor(currency string, rates ...request.Rate) request.Descriptor {
+	return request.Descriptor{
+		ID: "1",
+		Payload: request.Currency{
+			Name:  currency,
+			Rates: rates,
+		},
+	}
+}
+
+func newRate(date string, value float64) request.Rate {
+	return request.Rate{
+		Date:  newDate(date),
+		Value: value,
+	}
+}
+
+func newDate(date string) time.Time {
+	t, _ := time.Parse(time.DateOnly, date)
+	return t
+}
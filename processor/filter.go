@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/scheduler"
+)
+
+type Predicate func(request.Descriptor) bool
+
+func Succeeded(desc request.Descriptor) bool {
+	return !desc.Failed()
+}
+
+func Failed(desc request.Descriptor) bool {
+	return desc.Failed()
+}
+
+func WithErrorKinds(kinds ...request.ErrorKind) Predicate {
+	return func(desc request.Descriptor) bool {
+		for _, k := range kinds {
+			if desc.ErrorKind == k {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Filter passes to the next processor only descriptors matching the predicate.
+type Filter struct {
+	next      scheduler.Processor
+	predicate Predicate
+}
+
+func NewFilter(next scheduler.Processor, predicate Predicate) Filter {
+	return Filter{
+		next:      next,
+		predicate: predicate,
+	}
+}
+
+func (f Filter) Process(ctx context.Context, desc request.Descriptor) error {
+	if !f.predicate(desc) {
+		return nil
+	}
+	return f.next.Process(ctx, desc)
+}
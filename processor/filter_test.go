@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/scheduler/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldPassOnlyMatchingDescriptors(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	sut := NewFilter(procMock, WithErrorKinds(request.ErrorTransport, request.ErrorHTTPStatus))
+	succeeded := newRatesDescriptor("EUR", newRate("2023-10-03", 4.6))
+	transport := request.Descriptor{ID: "2", ErrorKind: request.ErrorTransport}
+	decode := request.Descriptor{ID: "3", ErrorKind: request.ErrorDecode}
+
+	procMock.EXPECT().Process(mock.Anything, transport).Return(nil).Once()
+
+	// when
+	errSucceeded := sut.Process(context.Background(), succeeded)
+	errTransport := sut.Process(context.Background(), transport)
+	errDecode := sut.Process(context.Background(), decode)
+
+	// then
+	assert.NoError(t, errSucceeded)
+	assert.NoError(t, errTransport)
+	assert.NoError(t, errDecode)
+}
+
+func TestShouldTellFailedFromSucceededDescriptors(t *testing.T) {
+	// given
+	empty := newRatesDescriptor("EUR")
+	classified := request.Descriptor{ErrorKind: request.ErrorPayload}
+	reported := request.Descriptor{Error: "requester failure"}
+
+	// then
+	assert.True(t, Succeeded(empty))
+	assert.False(t, Failed(empty))
+	assert.True(t, Failed(classified))
+	assert.True(t, Failed(reported))
+}
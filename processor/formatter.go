@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+type Formatter interface {
+	Format(w io.Writer, desc request.Descriptor) error
+}
+
+// TextFormatter writes descriptors the way request.Descriptor.WriteTo does.
+type TextFormatter struct{}
+
+func (TextFormatter) Format(w io.Writer, desc request.Descriptor) error {
+	_, err := desc.WriteTo(w)
+	return err
+}
+
+// JSONFormatter writes one JSON object per line.
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(w io.Writer, desc request.Descriptor) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(desc)
+}
+
+type LogfmtFormatter struct{}
+
+func (LogfmtFormatter) Format(w io.Writer, desc request.Descriptor) error {
+	var sb strings.Builder
+	for i, f := range logfmtFields(desc) {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.key)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtValue(f.value))
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type field struct {
+	key   string
+	value string
+}
+
+func logfmtFields(desc request.Descriptor) []field {
+	fields := []field{
+		{"id", desc.ID},
+		{"target", desc.Target},
+		{"url", desc.URL},
+		{"time", desc.Time.Format(time.RFC3339Nano)},
+		{"valid_status_code", strconv.FormatBool(desc.ValidStatusCode)},
+		{"json", strconv.FormatBool(desc.JSON)},
+		{"valid", strconv.FormatBool(desc.Valid)},
+		{"duration", desc.Duration.String()},
+		{"status_code", strconv.Itoa(desc.StatusCode)},
+		{"response_size", strconv.FormatInt(desc.ResponseSize, 10)},
+		{"attempts", strconv.Itoa(desc.Attempts)},
+		{"currency", desc.Payload.Name},
+		{"rates", strconv.Itoa(len(desc.Payload.Rates))},
+	}
+	if latest, ok := latestRate(desc); ok {
+		fields = append(fields,
+			field{"latest_date", latest.Date.Format(time.DateOnly)},
+			field{"latest_value", formatValue(latest.Value)})
+	}
+	if s := desc.Sample; s.Probes > 0 {
+		fields = append(fields,
+			field{"probes", strconv.Itoa(s.Probes)},
+			field{"succeeded", strconv.Itoa(s.Succeeded)},
+			field{"agreeing", strconv.Itoa(s.Agreeing)},
+			field{"quorum", strconv.FormatBool(s.Quorum)},
+			field{"disagreement", strconv.FormatBool(s.Disagreement)},
+			field{"p50", s.LatencyP50.String()},
+			field{"p90", s.LatencyP90.String()},
+			field{"p99", s.LatencyP99.String()})
+	}
+	if desc.Backfill {
+		fields = append(fields, field{"backfill", "true"})
+	}
+	if desc.ErrorKind != "" {
+		fields = append(fields, field{"error_kind", string(desc.ErrorKind)})
+	}
+	if desc.Error != "" {
+		fields = append(fields, field{"error", desc.Error})
+	}
+	return fields
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\\t\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// CSVFormatter writes one row per descriptor with a fixed set of columns,
+// preceded by a header row on the first call when header is set.
+type CSVFormatter struct {
+	header  bool
+	written bool
+}
+
+var csvFormatterColumns = []string{
+	"id", "target", "url", "time", "valid_status_code", "json", "valid", "duration_ms",
+	"status_code", "response_size", "attempts", "currency", "rates", "latest_date", "latest_value",
+	"backfill", "error_kind", "error",
+}
+
+// CSVHeader returns the header row written by CSVFormatter, for outputs that
+// start new files on their own such as rotated logs.
+func CSVHeader() []byte {
+	var header bytes.Buffer
+	writer := csv.NewWriter(&header)
+	writer.Write(csvFormatterColumns)
+	writer.Flush()
+	return header.Bytes()
+}
+
+func NewCSVFormatter(header bool) *CSVFormatter {
+	return &CSVFormatter{
+		header: header,
+	}
+}
+
+func (f *CSVFormatter) Format(w io.Writer, desc request.Descriptor) error {
+	writer := csv.NewWriter(w)
+	if f.header && !f.written {
+		if err := writer.Write(csvFormatterColumns); err != nil {
+			return err
+		}
+	}
+	f.written = true
+
+	var latestDate, latestValue string
+	if latest, ok := latestRate(desc); ok {
+		latestDate = latest.Date.Format(time.DateOnly)
+		latestValue = formatValue(latest.Value)
+	}
+	err := writer.Write([]string{
+		desc.ID,
+		desc.Target,
+		desc.URL,
+		desc.Time.Format(time.RFC3339Nano),
+		strconv.FormatBool(desc.ValidStatusCode),
+		strconv.FormatBool(desc.JSON),
+		strconv.FormatBool(desc.Valid),
+		strconv.FormatFloat(float64(desc.Duration)/float64(time.Millisecond), 'f', -1, 64),
+		strconv.Itoa(desc.StatusCode),
+		strconv.FormatInt(desc.ResponseSize, 10),
+		strconv.Itoa(desc.Attempts),
+		desc.Payload.Name,
+		strconv.Itoa(len(desc.Payload.Rates)),
+		latestDate,
+		latestValue,
+		strconv.FormatBool(desc.Backfill),
+		string(desc.ErrorKind),
+		desc.Error,
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// TemplateFormatter executes a text/template with the descriptor as data.
+// A newline is appended when the template output does not end with one.
+type TemplateFormatter struct {
+	tmpl *template.Template
+}
+
+func NewTemplateFormatter(text string) (*TemplateFormatter, error) {
+	tmpl, err := template.New("descriptor").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return &TemplateFormatter{
+		tmpl: tmpl,
+	}, nil
+}
+
+func (f *TemplateFormatter) Format(w io.Writer, desc request.Descriptor) error {
+	var sb strings.Builder
+	if err := f.tmpl.Execute(&sb, desc); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func latestRate(desc request.Descriptor) (request.Rate, bool) {
+	if len(desc.Payload.Rates) == 0 {
+		return request.Rate{}, false
+	}
+	latest := desc.Payload.Rates[0]
+	for _, r := range desc.Payload.Rates[1:] {
+		if r.Date.After(latest.Date) {
+			latest = r
+		}
+	}
+	return latest, true
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
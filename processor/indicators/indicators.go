@@ -0,0 +1,318 @@
+package indicators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	alertName = "indicators"
+
+	defaultPeriod          = 20
+	defaultRSIPeriod       = 14
+	defaultBollingerFactor = 2.0
+)
+
+const (
+	SignalCrossedAboveSMA = "crossed_above_sma"
+	SignalCrossedBelowSMA = "crossed_below_sma"
+	SignalAboveBollinger  = "above_bollinger"
+	SignalBelowBollinger  = "below_bollinger"
+	SignalInsideBollinger = "inside_bollinger"
+)
+
+type Config struct {
+	SMAPeriod       int
+	EMAPeriod       int
+	BollingerPeriod int
+	BollingerK      float64
+	RSIPeriod       int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SMAPeriod:       defaultPeriod,
+		EMAPeriod:       defaultPeriod,
+		BollingerPeriod: defaultPeriod,
+		BollingerK:      defaultBollingerFactor,
+		RSIPeriod:       defaultRSIPeriod,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.SMAPeriod <= 0 {
+		c.SMAPeriod = defaults.SMAPeriod
+	}
+	if c.EMAPeriod <= 0 {
+		c.EMAPeriod = defaults.EMAPeriod
+	}
+	if c.BollingerPeriod <= 0 {
+		c.BollingerPeriod = defaults.BollingerPeriod
+	}
+	if c.BollingerK <= 0 {
+		c.BollingerK = defaults.BollingerK
+	}
+	if c.RSIPeriod <= 0 {
+		c.RSIPeriod = defaults.RSIPeriod
+	}
+	return c
+}
+
+func (c Config) maxPeriod() int {
+	max := c.SMAPeriod
+	for _, p := range []int{c.EMAPeriod, c.BollingerPeriod} {
+		if p > max {
+			max = p
+		}
+	}
+	return max
+}
+
+type Values struct {
+	Date            time.Time
+	Rate            float64
+	Samples         int
+	SMA             float64
+	SMAReady        bool
+	EMA             float64
+	EMAReady        bool
+	BollingerUpper  float64
+	BollingerMiddle float64
+	BollingerLower  float64
+	BollingerReady  bool
+	RSI             float64
+	RSIReady        bool
+}
+
+type options struct {
+	sink  alert.Sink
+	store state.Store
+}
+
+type Option func(*options)
+
+func WithAlerts(sink alert.Sink) Option {
+	return func(o *options) {
+		o.sink = sink
+	}
+}
+
+// WithStore keeps the date of the latest rate checked for signals, so rates
+// replayed after a restart only rebuild the indicators.
+func WithStore(store state.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+type progress struct {
+	LastDate time.Time `json:"last_date"`
+}
+
+type Processor struct {
+	cfg     Config
+	options options
+
+	mtx    sync.Mutex
+	series map[string]*series
+}
+
+func New(cfg Config, opts ...Option) *Processor {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Processor{
+		cfg:     cfg.withDefaults(),
+		options: o,
+		series:  make(map[string]*series),
+	}
+}
+
+func (p *Processor) Process(ctx context.Context, desc request.Descriptor) error {
+	events, err := p.update(desc.Payload)
+	if len(events) == 0 || p.options.sink == nil {
+		return err
+	}
+	return errors.Join(err, p.options.sink.Notify(ctx, events))
+}
+
+func (p *Processor) Latest(currency string) (Values, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	s, ok := p.series[currency]
+	if !ok || s.count == 0 {
+		return Values{}, false
+	}
+	return s.values, true
+}
+
+func (p *Processor) Currencies() []string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	currencies := make([]string, 0, len(p.series))
+	for currency := range p.series {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+func (p *Processor) update(payload request.Currency) ([]alert.Event, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	s, err := p.currencySeries(payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	signalled := s.signalled
+
+	rates := make([]request.Rate, len(payload.Rates))
+	copy(rates, payload.Rates)
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+
+	// without a record of checked rates the first descriptor only builds the
+	// indicators, so a fresh start does not alert on signals long gone
+	warmUp := signalled.IsZero()
+	var events []alert.Event
+	for _, rate := range rates {
+		if s.count > 0 && !rate.Date.After(s.last) {
+			continue
+		}
+		previous := s.values
+		current := s.add(rate.Date, rate.Value)
+		if warmUp || !rate.Date.After(signalled) {
+			continue
+		}
+		for _, signal := range signals(previous, current) {
+			events = append(events, newEvent(payload.Name, rate, signal, current))
+		}
+	}
+	if !s.last.After(signalled) {
+		return events, nil
+	}
+	s.signalled = s.last
+	if p.options.store == nil {
+		return events, nil
+	}
+	return events, p.options.store.Save(stateKey(payload.Name), progress{LastDate: s.signalled})
+}
+
+// currencySeries must be called with mtx held.
+func (p *Processor) currencySeries(currency string) (*series, error) {
+	if s, ok := p.series[currency]; ok {
+		return s, nil
+	}
+	s := newSeries(p.cfg)
+	if p.options.store != nil {
+		var restored progress
+		err := p.options.store.Load(stateKey(currency), &restored)
+		if err != nil && !errors.Is(err, state.ErrNotFound) {
+			return nil, err
+		}
+		s.signalled = restored.LastDate
+	}
+	p.series[currency] = s
+	return s, nil
+}
+
+func stateKey(currency string) string {
+	return fmt.Sprintf("%s/%s", alertName, currency)
+}
+
+func signals(previous, current Values) []string {
+	var result []string
+	if previous.SMAReady && current.SMAReady {
+		switch {
+		case previous.Rate <= previous.SMA && current.Rate > current.SMA:
+			result = append(result, SignalCrossedAboveSMA)
+		case previous.Rate >= previous.SMA && current.Rate < current.SMA:
+			result = append(result, SignalCrossedBelowSMA)
+		}
+	}
+	if previous.BollingerReady && current.BollingerReady {
+		before, after := bollingerPosition(previous), bollingerPosition(current)
+		if before != after {
+			result = append(result, after)
+		}
+	}
+	return result
+}
+
+func bollingerPosition(v Values) string {
+	switch {
+	case v.Rate > v.BollingerUpper:
+		return SignalAboveBollinger
+	case v.Rate < v.BollingerLower:
+		return SignalBelowBollinger
+	default:
+		return SignalInsideBollinger
+	}
+}
+
+func newEvent(currency string, rate request.Rate, signal string, v Values) alert.Event {
+	event := alert.Event{
+		Key:      fmt.Sprintf("%s/%s/%s", alertName, currency, signalGroup(signal)),
+		Name:     alertName,
+		Currency: currency,
+		Rate:     rate,
+		Severity: alert.SeverityInfo,
+		Message:  fmt.Sprintf("%s rate %v %s", currency, rate.Value, describe(signal, v)),
+		Time:     time.Now(),
+		Labels: map[string]string{
+			"signal": signal,
+		},
+		Values: map[string]float64{
+			"value":            rate.Value,
+			"sma":              v.SMA,
+			"ema":              v.EMA,
+			"bollinger_upper":  v.BollingerUpper,
+			"bollinger_middle": v.BollingerMiddle,
+			"bollinger_lower":  v.BollingerLower,
+			"rsi":              v.RSI,
+		},
+	}
+	switch signal {
+	case SignalAboveBollinger, SignalBelowBollinger:
+		event.Severity = alert.SeverityWarning
+	case SignalInsideBollinger:
+		event.Resolved = true
+	}
+	return event
+}
+
+func signalGroup(signal string) string {
+	switch signal {
+	case SignalCrossedAboveSMA, SignalCrossedBelowSMA:
+		return "sma"
+	default:
+		return "bollinger"
+	}
+}
+
+func describe(signal string, v Values) string {
+	switch signal {
+	case SignalCrossedAboveSMA:
+		return fmt.Sprintf("crossed above SMA %.4f", v.SMA)
+	case SignalCrossedBelowSMA:
+		return fmt.Sprintf("crossed below SMA %.4f", v.SMA)
+	case SignalAboveBollinger:
+		return fmt.Sprintf("above upper Bollinger band %.4f", v.BollingerUpper)
+	case SignalBelowBollinger:
+		return fmt.Sprintf("below lower Bollinger band %.4f", v.BollingerLower)
+	default:
+		return fmt.Sprintf("back inside Bollinger bands [%.4f, %.4f]", v.BollingerLower, v.BollingerUpper)
+	}
+}
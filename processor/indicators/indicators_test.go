@@ -0,0 +1,168 @@
+package indicators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldComputeIndicatorsForCurrency(t *testing.T) {
+	// given
+	sut := New(smallConfig())
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 1, 2, 3, 4, 5))
+
+	// then
+	assert.NoError(t, err)
+	values, ok := sut.Latest("EUR")
+	assert.True(t, ok)
+	assert.Equal(t, 5, values.Samples)
+	assert.True(t, values.SMAReady)
+	assert.InDelta(t, 4.0, values.SMA, 1e-9)
+	assert.True(t, values.EMAReady)
+	assert.InDelta(t, 4.0, values.EMA, 1e-9)
+	assert.True(t, values.BollingerReady)
+	assert.InDelta(t, 4.0, values.BollingerMiddle, 1e-9)
+	assert.InDelta(t, 5.63299, values.BollingerUpper, 1e-5)
+	assert.InDelta(t, 2.36701, values.BollingerLower, 1e-5)
+	assert.True(t, values.RSIReady)
+	assert.InDelta(t, 100.0, values.RSI, 1e-9)
+}
+
+func TestShouldComputeRSIFromGainsAndLosses(t *testing.T) {
+	// given
+	sut := New(smallConfig())
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 10, 11, 10, 12))
+
+	// then
+	assert.NoError(t, err)
+	values, _ := sut.Latest("EUR")
+	assert.InDelta(t, 75.0, values.RSI, 1e-9)
+}
+
+func TestShouldReportNeutralRSIForFlatRates(t *testing.T) {
+	// given
+	sut := New(smallConfig())
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 4.5, 4.5, 4.5, 4.5))
+
+	// then
+	assert.NoError(t, err)
+	values, _ := sut.Latest("EUR")
+	assert.True(t, values.RSIReady)
+	assert.InDelta(t, 50.0, values.RSI, 1e-9)
+}
+
+func TestShouldNotReportIndicatorsDuringWarmUp(t *testing.T) {
+	// given
+	sut := New(smallConfig())
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 1, 2))
+
+	// then
+	assert.NoError(t, err)
+	values, ok := sut.Latest("EUR")
+	assert.True(t, ok)
+	assert.False(t, values.SMAReady)
+	assert.False(t, values.EMAReady)
+	assert.False(t, values.BollingerReady)
+	assert.False(t, values.RSIReady)
+	_, ok = sut.Latest("USD")
+	assert.False(t, ok)
+}
+
+func TestShouldNotifyWhenRateCrossesAboveSMA(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := New(smallConfig(), WithAlerts(sinkMock))
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Once()
+	assert.NoError(t, sut.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1)))
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1, 2))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, SignalCrossedAboveSMA, notified[0].Labels["signal"])
+	assert.Equal(t, "indicators/EUR/sma", notified[0].Key)
+}
+
+func TestShouldOnlyBuildIndicatorsFromFirstDescriptor(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := New(smallConfig(), WithAlerts(sinkMock))
+
+	// when
+	err := sut.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1, 2, 1, 1, 1, 3))
+
+	// then
+	assert.NoError(t, err)
+	values, ok := sut.Latest("EUR")
+	assert.True(t, ok)
+	assert.Equal(t, 9, values.Samples)
+}
+
+func TestShouldNotRepeatSignalsAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	firstSink := mocks.NewSink(t)
+	firstSink.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	first := New(smallConfig(), WithAlerts(firstSink), WithStore(store))
+	assert.NoError(t, first.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1)))
+	assert.NoError(t, first.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1, 2)))
+
+	// when
+	sut := New(smallConfig(), WithAlerts(mocks.NewSink(t)), WithStore(store))
+	err := sut.Process(context.Background(), newDescriptor("EUR", 1, 1, 1, 1, 2))
+
+	// then
+	assert.NoError(t, err)
+	latest, ok := sut.Latest("EUR")
+	assert.True(t, ok)
+	assert.True(t, latest.SMAReady)
+}
+
+func smallConfig() Config {
+	return Config{
+		SMAPeriod:       3,
+		EMAPeriod:       3,
+		BollingerPeriod: 3,
+		BollingerK:      2,
+		RSIPeriod:       3,
+	}
+}
+
+func newDescriptor(currency string, values ...float64) request.Descriptor {
+	start := time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+	var rates []request.Rate
+	for i, v := range values {
+		rates = append(rates, request.Rate{
+			Date:  start.AddDate(0, 0, i),
+			Value: v,
+		})
+	}
+	return request.Descriptor{
+		ID: "1",
+		Payload: request.Currency{
+			Name:  currency,
+			Rates: rates,
+		},
+	}
+}
@@ -0,0 +1,142 @@
+package indicators
+
+import (
+	"math"
+	"time"
+)
+
+type series struct {
+	cfg    Config
+	window []float64
+	last   time.Time
+	count  int
+	// signalled is the date of the latest rate checked for signals,
+	// possibly before a restart.
+	signalled time.Time
+
+	ema float64
+
+	previous float64
+	changes  int
+	avgGain  float64
+	avgLoss  float64
+
+	values Values
+}
+
+func newSeries(cfg Config) *series {
+	return &series{
+		cfg: cfg,
+	}
+}
+
+func (s *series) add(date time.Time, value float64) Values {
+	s.count++
+	s.window = append(s.window, value)
+	if len(s.window) > s.cfg.maxPeriod() {
+		s.window = s.window[len(s.window)-s.cfg.maxPeriod():]
+	}
+	s.last = date
+
+	v := Values{
+		Date:    date,
+		Rate:    value,
+		Samples: s.count,
+	}
+	s.updateSMA(&v)
+	s.updateEMA(&v, value)
+	s.updateBollinger(&v)
+	s.updateRSI(&v, value)
+	s.values = v
+	return v
+}
+
+func (s *series) updateSMA(v *Values) {
+	if s.count < s.cfg.SMAPeriod {
+		return
+	}
+	v.SMA = mean(s.tail(s.cfg.SMAPeriod))
+	v.SMAReady = true
+}
+
+func (s *series) updateEMA(v *Values, value float64) {
+	switch {
+	case s.count < s.cfg.EMAPeriod:
+		return
+	case s.count == s.cfg.EMAPeriod:
+		s.ema = mean(s.tail(s.cfg.EMAPeriod))
+	default:
+		alpha := 2 / float64(s.cfg.EMAPeriod+1)
+		s.ema = alpha*value + (1-alpha)*s.ema
+	}
+	v.EMA = s.ema
+	v.EMAReady = true
+}
+
+func (s *series) updateBollinger(v *Values) {
+	if s.count < s.cfg.BollingerPeriod {
+		return
+	}
+	window := s.tail(s.cfg.BollingerPeriod)
+	middle := mean(window)
+	deviation := stddev(window, middle)
+	v.BollingerMiddle = middle
+	v.BollingerUpper = middle + s.cfg.BollingerK*deviation
+	v.BollingerLower = middle - s.cfg.BollingerK*deviation
+	v.BollingerReady = true
+}
+
+// updateRSI uses Wilder's smoothing of average gains and losses.
+func (s *series) updateRSI(v *Values, value float64) {
+	if s.count == 1 {
+		s.previous = value
+		return
+	}
+	change := value - s.previous
+	s.previous = value
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+	s.changes++
+	period := float64(s.cfg.RSIPeriod)
+	if s.changes <= s.cfg.RSIPeriod {
+		s.avgGain += gain / period
+		s.avgLoss += loss / period
+		if s.changes < s.cfg.RSIPeriod {
+			return
+		}
+	} else {
+		s.avgGain = (s.avgGain*(period-1) + gain) / period
+		s.avgLoss = (s.avgLoss*(period-1) + loss) / period
+	}
+
+	switch {
+	case s.avgLoss > 0:
+		v.RSI = 100 - 100/(1+s.avgGain/s.avgLoss)
+	case s.avgGain > 0:
+		v.RSI = 100
+	default:
+		// a flat series has neither momentum up nor down
+		v.RSI = 50
+	}
+	v.RSIReady = true
+}
+
+func (s *series) tail(n int) []float64 {
+	return s.window[len(s.window)-n:]
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stddev(values []float64, mean float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
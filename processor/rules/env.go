@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+type env struct {
+	currency string
+	rate     request.Rate
+	history  []request.Rate
+}
+
+func (e *env) rateBefore(arg any) (float64, error) {
+	var lookback time.Duration
+	switch v := arg.(type) {
+	case time.Duration:
+		lookback = v
+	case float64:
+		lookback = time.Duration(v * float64(24*time.Hour))
+	default:
+		return 0, fmt.Errorf("%w: expected duration, got %T", ErrType, arg)
+	}
+	date := e.rate.Date.Add(-lookback)
+	idx := sort.Search(len(e.history), func(i int) bool {
+		return e.history[i].Date.After(date)
+	})
+	if idx == 0 {
+		return 0, fmt.Errorf("%w: no rate on or before %s", ErrNoData, date.Format(time.DateOnly))
+	}
+	return e.history[idx-1].Value, nil
+}
+
+func (e *env) window(n int) ([]float64, error) {
+	if len(e.history)+1 < n {
+		return nil, fmt.Errorf("%w: %d rates required, %d available", ErrNoData, n, len(e.history)+1)
+	}
+	values := make([]float64, 0, n)
+	for _, rate := range e.history[len(e.history)-n+1:] {
+		values = append(values, rate.Value)
+	}
+	return append(values, e.rate.Value), nil
+}
+
+func (e *env) reduce(arg any, fn func(a, b float64) float64) (any, error) {
+	n, err := count(arg)
+	if err != nil {
+		return nil, err
+	}
+	window, err := e.window(n)
+	if err != nil {
+		return nil, err
+	}
+	result := window[0]
+	for _, v := range window[1:] {
+		result = fn(result, v)
+	}
+	return result, nil
+}
@@ -0,0 +1,439 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrSyntax = errors.New("syntax error")
+	ErrType   = errors.New("type error")
+	ErrNoData = errors.New("not enough data")
+)
+
+type node interface {
+	eval(env *env) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+func (n literal) eval(env *env) (any, error) {
+	return n.value, nil
+}
+
+type variable struct {
+	name string
+}
+
+func (n variable) eval(env *env) (any, error) {
+	switch n.name {
+	case "currency":
+		return env.currency, nil
+	case "rate":
+		return env.rate.Value, nil
+	case "date":
+		return env.rate.Date.Format(time.DateOnly), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown variable %s", ErrSyntax, n.name)
+	}
+}
+
+type unary struct {
+	op      string
+	operand node
+}
+
+func (n unary) eval(env *env) (any, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: ! expects bool, got %T", ErrType, v)
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: - expects number, got %T", ErrType, v)
+		}
+		return -f, nil
+	}
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (n binary) eval(env *env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects bool, got %T", ErrType, n.op, left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects bool, got %T", ErrType, n.op, right)
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects numbers, got %T and %T", ErrType, n.op, left, right)
+		}
+		return arithmetic(n.op, l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects strings, got %T and %T", ErrType, n.op, left, right)
+		}
+		return compareStrings(n.op, l, r)
+	default:
+		return nil, fmt.Errorf("%w: %s not supported for %T", ErrType, n.op, left)
+	}
+}
+
+func arithmetic(op string, l, r float64) (any, error) {
+	switch op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("%w: division by zero", ErrNoData)
+		}
+		return l / r, nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %s", ErrSyntax, op)
+}
+
+func compareStrings(op string, l, r string) (any, error) {
+	switch op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("%w: %s not supported for strings", ErrType, op)
+}
+
+type call struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n call) eval(env *env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(env, args)
+}
+
+type function struct {
+	arity int
+	call  func(env *env, args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"change": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			past, err := env.rateBefore(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return env.rate.Value - past, nil
+		},
+	},
+	"change_pct": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			past, err := env.rateBefore(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if past == 0 {
+				return nil, fmt.Errorf("%w: zero base rate", ErrNoData)
+			}
+			return (env.rate.Value - past) / past * 100, nil
+		},
+	},
+	"sma": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			n, err := count(args[0])
+			if err != nil {
+				return nil, err
+			}
+			window, err := env.window(n)
+			if err != nil {
+				return nil, err
+			}
+			var sum float64
+			for _, v := range window {
+				sum += v
+			}
+			return sum / float64(len(window)), nil
+		},
+	},
+	"min": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			return env.reduce(args[0], math.Min)
+		},
+	},
+	"max": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			return env.reduce(args[0], math.Max)
+		},
+	},
+	"abs": {
+		arity: 1,
+		call: func(env *env, args []any) (any, error) {
+			f, ok := args[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: abs expects number, got %T", ErrType, args[0])
+			}
+			return math.Abs(f), nil
+		},
+	},
+}
+
+func count(arg any) (int, error) {
+	f, ok := arg.(float64)
+	if !ok || f < 1 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%w: expected positive integer, got %v", ErrType, arg)
+	}
+	return int(f), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func compile(input string) (node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, t.text, t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOperator("==", "!=", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/")
+}
+
+func (p *parser) parseBinary(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.acceptOperator("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return literal{value: t.number}, nil
+	case tokenString:
+		return literal{value: t.text}, nil
+	case tokenDuration:
+		return literal{value: t.duration}, nil
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("%w: expected ) at %d", ErrSyntax, closing.pos)
+		}
+		return n, nil
+	case tokenIdent:
+		return p.parseIdent(t)
+	case tokenEOF:
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, t.text, t.pos)
+	}
+}
+
+func (p *parser) parseIdent(t token) (node, error) {
+	switch t.text {
+	case "true":
+		return literal{value: true}, nil
+	case "false":
+		return literal{value: false}, nil
+	case "currency", "rate", "date":
+		if p.peek().kind != tokenLParen {
+			return variable{name: t.text}, nil
+		}
+	}
+
+	fn, ok := functions[t.text]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown identifier %s at %d", ErrSyntax, t.text, t.pos)
+	}
+	if open := p.next(); open.kind != tokenLParen {
+		return nil, fmt.Errorf("%w: expected ( after %s at %d", ErrSyntax, t.text, open.pos)
+	}
+	var args []node
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokenRParen {
+		return nil, fmt.Errorf("%w: expected ) at %d", ErrSyntax, closing.pos)
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%w: %s expects %d argument(s), got %d", ErrSyntax, t.text, fn.arity, len(args))
+	}
+	return call{name: t.text, fn: fn, args: args}, nil
+}
@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldEvaluateExpressions(t *testing.T) {
+	history := []request.Rate{
+		newRate("2023-09-29", 4.60),
+		newRate("2023-10-02", 4.62),
+		newRate("2023-10-03", 4.64),
+	}
+	e := &env{
+		currency: "EUR",
+		rate:     newRate("2023-10-04", 4.70),
+		history:  history,
+	}
+	testCases := []struct {
+		expr     string
+		expected any
+	}{
+		{expr: `currency == "EUR" && rate > 4.65`, expected: true},
+		{expr: `currency != "EUR" || rate < 4.65`, expected: false},
+		{expr: `!(rate >= 4.7)`, expected: false},
+		{expr: `rate * 2 - 0.4 == 9`, expected: true},
+		{expr: `-rate < 0`, expected: true},
+		{expr: `date == "2023-10-04"`, expected: true},
+		{expr: `change(1d) > 0.059 && change(1d) < 0.061`, expected: true},
+		{expr: `change(2) > 0.079`, expected: true},
+		{expr: `change_pct(5d) > 2.17 && change_pct(5d) < 2.18`, expected: true},
+		{expr: `sma(2) == (4.64 + 4.70) / 2`, expected: true},
+		{expr: `max(4) == 4.70 && min(4) == 4.60`, expected: true},
+		{expr: `abs(4.6 - rate) > 0.09`, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			// given
+			n, err := compile(tc.expr)
+			assert.NoError(t, err)
+
+			// when
+			result, err := n.eval(e)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestShouldRejectInvalidExpressions(t *testing.T) {
+	testCases := []string{
+		`rate >`,
+		`rate > 4.7)`,
+		`price > 4.7`,
+		`change_pct() > 1`,
+		`currency == "EUR`,
+		`rate # 4`,
+	}
+	for _, expr := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			// when
+			_, err := compile(expr)
+
+			// then
+			assert.ErrorIs(t, err, ErrSyntax)
+		})
+	}
+}
+
+func TestShouldReportTypeErrors(t *testing.T) {
+	// given
+	n, err := compile(`currency > 4 && rate`)
+	assert.NoError(t, err)
+
+	// when
+	_, err = n.eval(&env{currency: "EUR", rate: newRate("2023-10-04", 4.7)})
+
+	// then
+	assert.ErrorIs(t, err, ErrType)
+}
+
+func TestShouldReportMissingHistory(t *testing.T) {
+	// given
+	n, err := compile(`change_pct(7d) > 1`)
+	assert.NoError(t, err)
+
+	// when
+	_, err = n.eval(&env{currency: "EUR", rate: newRate("2023-10-04", 4.7)})
+
+	// then
+	assert.ErrorIs(t, err, ErrNoData)
+}
+
+func TestShouldParseDayAndWeekDurations(t *testing.T) {
+	// when
+	day, errDay := ParseDuration("1d")
+	week, errWeek := ParseDuration("2w")
+	hours, errHours := ParseDuration("6h")
+
+	// then
+	assert.NoError(t, errDay)
+	assert.NoError(t, errWeek)
+	assert.NoError(t, errHours)
+	assert.Equal(t, 24*time.Hour, day)
+	assert.Equal(t, 14*24*time.Hour, week)
+	assert.Equal(t, 6*time.Hour, hours)
+}
+
+func newRate(date string, value float64) request.Rate {
+	d, _ := time.Parse(time.DateOnly, date)
+	return request.Rate{
+		Date:  d,
+		Value: value,
+	}
+}
@@ -0,0 +1,418 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	defaultMessage = "rule {{.Rule}} triggered for {{.Currency}} rate {{.Rate}} on {{.Date}}"
+	maxHistory     = 400
+	statePrefix    = "rules"
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid rule")
+)
+
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+type Rule struct {
+	Name     string         `json:"name"`
+	Expr     string         `json:"expr"`
+	Severity alert.Severity `json:"severity"`
+	Cooldown Duration       `json:"cooldown"`
+	Message  string         `json:"message"`
+}
+
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+func Load(r io.Reader) ([]Rule, error) {
+	var f file
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("unable to decode rules: %v", err)
+	}
+	return f.Rules, nil
+}
+
+func LoadFile(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open rules file: %v", err)
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+type MessageData struct {
+	Rule     string
+	Severity alert.Severity
+	Currency string
+	Rate     float64
+	Date     string
+	Expr     string
+}
+
+type compiledRule struct {
+	Rule
+	expr     node
+	message  *template.Template
+	lastFire map[string]time.Time
+}
+
+func compileRules(rules []Rule) ([]*compiledRule, error) {
+	var (
+		compiled []*compiledRule
+		errs     []error
+		names    = make(map[string]bool)
+	)
+	for _, r := range rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("%w: rule without name", ErrInvalidRule))
+			continue
+		}
+		if names[r.Name] {
+			errs = append(errs, fmt.Errorf("%w: duplicated rule %s", ErrInvalidRule, r.Name))
+			continue
+		}
+		names[r.Name] = true
+		if r.Severity == "" {
+			r.Severity = alert.SeverityWarning
+		}
+		if !r.Severity.Valid() {
+			errs = append(errs, fmt.Errorf("%w: rule %s has unknown severity %q", ErrInvalidRule, r.Name, r.Severity))
+			continue
+		}
+		expr, err := compile(r.Expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: rule %s: %w", ErrInvalidRule, r.Name, err))
+			continue
+		}
+		message := r.Message
+		if message == "" {
+			message = defaultMessage
+		}
+		tmpl, err := template.New(r.Name).Option("missingkey=error").Parse(message)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: rule %s message: %v", ErrInvalidRule, r.Name, err))
+			continue
+		}
+		compiled = append(compiled, &compiledRule{
+			Rule:     r,
+			expr:     expr,
+			message:  tmpl,
+			lastFire: make(map[string]time.Time),
+		})
+	}
+	return compiled, errors.Join(errs...)
+}
+
+func Validate(rules []Rule) error {
+	_, err := compileRules(rules)
+	return err
+}
+
+type Match struct {
+	Rule     string
+	Severity alert.Severity
+	Currency string
+	Rate     request.Rate
+	Message  string
+}
+
+func (r *compiledRule) evaluate(e *env, at time.Time) (Match, bool, error) {
+	if last, ok := r.lastFire[e.currency]; ok && at.Sub(last) < r.Cooldown.Duration {
+		return Match{}, false, nil
+	}
+	result, err := r.expr.eval(e)
+	if errors.Is(err, ErrNoData) {
+		return Match{}, false, nil
+	}
+	if err != nil {
+		return Match{}, false, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return Match{}, false, fmt.Errorf("rule %s: %w: expression returned %T instead of bool", r.Name, ErrType, result)
+	}
+	if !matched {
+		return Match{}, false, nil
+	}
+	r.lastFire[e.currency] = at
+
+	var message bytes.Buffer
+	err = r.message.Execute(&message, MessageData{
+		Rule:     r.Name,
+		Severity: r.Severity,
+		Currency: e.currency,
+		Rate:     e.rate.Value,
+		Date:     e.rate.Date.Format(time.DateOnly),
+		Expr:     r.Expr,
+	})
+	if err != nil {
+		return Match{}, false, fmt.Errorf("rule %s message: %v", r.Name, err)
+	}
+	return Match{
+		Rule:     r.Name,
+		Severity: r.Severity,
+		Currency: e.currency,
+		Rate:     e.rate,
+		Message:  strings.TrimSpace(message.String()),
+	}, true, nil
+}
+
+type options struct {
+	store state.Store
+}
+
+type Option func(*options)
+
+// WithStore keeps the date of the latest rate evaluated and the time each
+// rule last fired, so rates replayed after a restart only rebuild the history
+// and cooldowns outlast restarts.
+func WithStore(store state.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+type progress struct {
+	LastDate time.Time            `json:"last_date"`
+	LastFire map[string]time.Time `json:"last_fire,omitempty"`
+}
+
+type Engine struct {
+	sink    alert.Sink
+	now     func() time.Time
+	rules   []*compiledRule
+	options options
+
+	mtx       sync.Mutex
+	histories map[string][]request.Rate
+	evaluated map[string]time.Time
+}
+
+func New(sink alert.Sink, rules []Rule, opts ...Option) (*Engine, error) {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	var cfg options
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &Engine{
+		sink:      sink,
+		now:       time.Now,
+		rules:     compiled,
+		options:   cfg,
+		histories: make(map[string][]request.Rate),
+		evaluated: make(map[string]time.Time),
+	}, nil
+}
+
+func (e *Engine) Process(ctx context.Context, desc request.Descriptor) error {
+	matches, err := e.evaluate(desc.Payload, func(request.Rate) time.Time {
+		return e.now()
+	}, true)
+	if len(matches) > 0 {
+		events := make([]alert.Event, 0, len(matches))
+		for _, m := range matches {
+			events = append(events, m.event(e.now()))
+		}
+		err = errors.Join(err, e.sink.Notify(ctx, events))
+	}
+	return err
+}
+
+// evaluate checks the rules against rates newer than the known history of
+// the currency. In live mode rates up to the latest one evaluated, possibly
+// before a restart, just make up the history and a currency seen for the
+// first time has only its latest rate checked.
+func (e *Engine) evaluate(payload request.Currency, clock func(request.Rate) time.Time, live bool) ([]Match, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	history := e.histories[payload.Name]
+	var evaluated time.Time
+	if live {
+		var err error
+		if evaluated, err = e.lastEvaluated(payload.Name); err != nil {
+			return nil, err
+		}
+	}
+	rates := make([]request.Rate, len(payload.Rates))
+	copy(rates, payload.Rates)
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	warmup := live && len(history) == 0 && evaluated.IsZero()
+
+	var (
+		matches []Match
+		errs    []error
+	)
+	for i, rate := range rates {
+		if len(history) > 0 && !rate.Date.After(history[len(history)-1].Date) {
+			continue
+		}
+		if !rate.Date.After(evaluated) || (warmup && i < len(rates)-1) {
+			history = appendHistory(history, rate)
+			continue
+		}
+		current := &env{
+			currency: payload.Name,
+			rate:     rate,
+			history:  history,
+		}
+		for _, r := range e.rules {
+			match, ok, err := r.evaluate(current, clock(rate))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				matches = append(matches, match)
+			}
+		}
+		history = appendHistory(history, rate)
+	}
+	e.histories[payload.Name] = history
+	if live && len(history) > 0 && history[len(history)-1].Date.After(evaluated) {
+		errs = append(errs, e.saveEvaluated(payload.Name, history[len(history)-1].Date))
+	}
+	return matches, errors.Join(errs...)
+}
+
+func appendHistory(history []request.Rate, rate request.Rate) []request.Rate {
+	history = append(history, rate)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// lastEvaluated must be called with mtx held.
+func (e *Engine) lastEvaluated(currency string) (time.Time, error) {
+	if date, ok := e.evaluated[currency]; ok || e.options.store == nil {
+		return date, nil
+	}
+	var restored progress
+	err := e.options.store.Load(stateKey(currency), &restored)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return time.Time{}, err
+	}
+	e.evaluated[currency] = restored.LastDate
+	for _, r := range e.rules {
+		if fired, ok := restored.LastFire[r.Name]; ok {
+			r.lastFire[currency] = fired
+		}
+	}
+	return restored.LastDate, nil
+}
+
+// saveEvaluated must be called with mtx held.
+func (e *Engine) saveEvaluated(currency string, date time.Time) error {
+	e.evaluated[currency] = date
+	if e.options.store == nil {
+		return nil
+	}
+	saved := progress{
+		LastDate: date,
+		LastFire: make(map[string]time.Time),
+	}
+	for _, r := range e.rules {
+		if fired, ok := r.lastFire[currency]; ok {
+			saved.LastFire[r.Name] = fired
+		}
+	}
+	return e.options.store.Save(stateKey(currency), saved)
+}
+
+func stateKey(currency string) string {
+	return fmt.Sprintf("%s/%s", statePrefix, currency)
+}
+
+// DryRun evaluates rules against historic rates, applying cooldowns in rate time.
+func DryRun(rules []Rule, currencies ...request.Currency) ([]Match, error) {
+	e, err := New(nil, rules)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		matches []Match
+		errs    []error
+	)
+	for _, c := range currencies {
+		m, err := e.evaluate(c, func(rate request.Rate) time.Time {
+			return rate.Date
+		}, false)
+		matches = append(matches, m...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return matches, errors.Join(errs...)
+}
+
+func (m Match) event(at time.Time) alert.Event {
+	return alert.Event{
+		Key:      fmt.Sprintf("rules/%s/%s", m.Rule, m.Currency),
+		Name:     m.Rule,
+		Currency: m.Currency,
+		Rate:     m.Rate,
+		Severity: m.Severity,
+		Message:  m.Message,
+		Time:     at,
+		Labels: map[string]string{
+			"rule": m.Rule,
+		},
+		Values: map[string]float64{
+			"value": m.Rate.Value,
+		},
+	}
+}
+
+func (m Match) WriteTo(w io.Writer) (int64, error) {
+	str := fmt.Sprintf("match rule=%v currency=%v date=%v price=%v severity=%v message=%q\n",
+		m.Rule, m.Currency, m.Rate.Date.Format(time.DateOnly), m.Rate.Value, m.Severity, m.Message)
+	n, err := io.WriteString(w, str)
+	return int64(n), err
+}
@@ -0,0 +1,230 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldLoadRulesFromJSON(t *testing.T) {
+	// given
+	content := `{"rules": [{
+		"name": "eur-high",
+		"expr": "currency == \"EUR\" && rate > 4.7",
+		"severity": "critical",
+		"cooldown": "2d",
+		"message": "{{.Currency}} is {{.Rate}}"
+	}]}`
+
+	// when
+	rules, err := Load(strings.NewReader(content))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{
+		Name:     "eur-high",
+		Expr:     `currency == "EUR" && rate > 4.7`,
+		Severity: alert.SeverityCritical,
+		Cooldown: Duration{48 * time.Hour},
+		Message:  "{{.Currency}} is {{.Rate}}",
+	}}, rules)
+}
+
+func TestShouldReportAllInvalidRules(t *testing.T) {
+	// given
+	rules := []Rule{
+		{Name: "ok", Expr: "rate > 1"},
+		{Name: "broken", Expr: "rate >"},
+		{Name: "ok", Expr: "rate > 2"},
+		{Expr: "rate > 3"},
+		{Name: "template", Expr: "rate > 1", Message: "{{.Rate"},
+		{Name: "severity", Expr: "rate > 1", Severity: "high"},
+	}
+
+	// when
+	err := Validate(rules)
+
+	// then
+	assert.ErrorIs(t, err, ErrInvalidRule)
+	assert.Contains(t, err.Error(), "broken")
+	assert.Contains(t, err.Error(), "duplicated rule ok")
+	assert.Contains(t, err.Error(), "without name")
+	assert.Contains(t, err.Error(), "template")
+	assert.Contains(t, err.Error(), `rule severity has unknown severity "high"`)
+}
+
+func TestShouldNotifyWithTemplatedMessage(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut, err := New(sinkMock, []Rule{{
+		Name:     "eur-jump",
+		Expr:     `currency == "EUR" && change_pct(1d) > 0.5`,
+		Severity: alert.SeverityCritical,
+		Message:  "{{.Currency}} jumped to {{.Rate}} on {{.Date}}",
+	}})
+	assert.NoError(t, err)
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Once()
+
+	// when
+	err = sut.Process(context.Background(), newDescriptor("EUR",
+		newRate("2023-10-02", 4.50),
+		newRate("2023-10-03", 4.51),
+		newRate("2023-10-04", 4.60),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, "eur-jump", notified[0].Name)
+	assert.Equal(t, "rules/eur-jump/EUR", notified[0].Key)
+	assert.Equal(t, alert.SeverityCritical, notified[0].Severity)
+	assert.Equal(t, "EUR jumped to 4.6 on 2023-10-04", notified[0].Message)
+}
+
+func TestShouldEvaluateOnlyRatesNewerThanHistory(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut, err := New(sinkMock, []Rule{{
+		Name:     "high",
+		Expr:     "rate > 4.7",
+		Cooldown: Duration{24 * time.Hour},
+	}})
+	assert.NoError(t, err)
+	now := time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC)
+	sut.now = func() time.Time { return now }
+	history := make([]request.Rate, 0, 100)
+	start := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		value := 4.5
+		if i == 5 || i == 99 {
+			value = 4.8
+		}
+		history = append(history, request.Rate{Date: start.AddDate(0, 0, i), Value: value})
+	}
+	next := append(history[1:], request.Rate{Date: start.AddDate(0, 0, 100), Value: 4.9})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Twice()
+
+	// when
+	errFirst := sut.Process(context.Background(), newDescriptor("EUR", history...))
+	errRepeated := sut.Process(context.Background(), newDescriptor("EUR", history...))
+	now = now.Add(48 * time.Hour)
+	errNext := sut.Process(context.Background(), newDescriptor("EUR", next...))
+
+	// then
+	assert.NoError(t, errors.Join(errFirst, errRepeated, errNext))
+	var dates []string
+	for _, e := range notified {
+		dates = append(dates, e.Rate.Date.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2023-10-08", "2023-10-09"}, dates)
+}
+
+func TestShouldNotEvaluateRatesReplayedAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	rules := []Rule{{Name: "high", Expr: "rate > 4.7"}}
+	history := newDescriptor("EUR", newRate("2023-10-02", 4.8), newRate("2023-10-03", 4.75))
+	firstSink := mocks.NewSink(t)
+	firstSink.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	first, err := New(firstSink, rules, WithStore(store))
+	assert.NoError(t, err)
+	assert.NoError(t, first.Process(context.Background(), history))
+
+	sinkMock := mocks.NewSink(t)
+	sut, err := New(sinkMock, rules, WithStore(store))
+	assert.NoError(t, err)
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	}).Once()
+
+	// when
+	errReplayed := sut.Process(context.Background(), history)
+	errNext := sut.Process(context.Background(), newDescriptor("EUR",
+		newRate("2023-10-03", 4.75), newRate("2023-10-04", 4.9)))
+
+	// then
+	assert.NoError(t, errReplayed)
+	assert.NoError(t, errNext)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, newRate("2023-10-04", 4.9), notified[0].Rate)
+}
+
+func TestShouldKeepCooldownAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	rules := []Rule{{Name: "high", Expr: "rate > 4.7", Cooldown: Duration{time.Hour}}}
+	firstSink := mocks.NewSink(t)
+	firstSink.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	first, err := New(firstSink, rules, WithStore(store))
+	assert.NoError(t, err)
+	assert.NoError(t, first.Process(context.Background(), newDescriptor("EUR", newRate("2023-10-02", 4.8))))
+
+	sut, err := New(mocks.NewSink(t), rules, WithStore(store))
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Process(context.Background(), newDescriptor("EUR",
+		newRate("2023-10-02", 4.8), newRate("2023-10-03", 4.9)))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldReportHistoricMatchesRespectingCooldown(t *testing.T) {
+	// given
+	rules := []Rule{{
+		Name:     "high",
+		Expr:     "rate > 4.6",
+		Cooldown: Duration{2 * 24 * time.Hour},
+	}}
+	history := request.Currency{
+		Name: "EUR",
+		Rates: []request.Rate{
+			newRate("2023-10-02", 4.65),
+			newRate("2023-10-03", 4.66),
+			newRate("2023-10-04", 4.67),
+			newRate("2023-10-05", 4.50),
+			newRate("2023-10-06", 4.68),
+		},
+	}
+
+	// when
+	matches, err := DryRun(rules, history)
+
+	// then
+	assert.NoError(t, err)
+	var dates []string
+	for _, m := range matches {
+		dates = append(dates, m.Rate.Date.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2023-10-02", "2023-10-04", "2023-10-06"}, dates)
+}
+
+func newDescriptor(currency string, rates ...request.Rate) request.Descriptor {
+	return request.Descriptor{
+		ID: "1",
+		Payload: request.Currency{
+			Name:  currency,
+			Rates: rates,
+		},
+	}
+}
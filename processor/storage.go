@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+)
+
+type storageOptions struct {
+	descriptors store.DescriptorLog
+}
+
+type StorageOption func(*storageOptions)
+
+func WithDescriptorLog(descriptors store.DescriptorLog) StorageOption {
+	return func(o *storageOptions) {
+		o.descriptors = descriptors
+	}
+}
+
+type Storage struct {
+	rates   store.RateStore
+	options storageOptions
+}
+
+func NewStorage(rates store.RateStore, opts ...StorageOption) Storage {
+	cfg := storageOptions{}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return Storage{
+		rates:   rates,
+		options: cfg,
+	}
+}
+
+func (s Storage) Process(ctx context.Context, desc request.Descriptor) error {
+	if s.options.descriptors != nil {
+		if err := s.options.descriptors.AppendDescriptor(ctx, desc); err != nil {
+			return err
+		}
+	}
+	if len(desc.Payload.Rates) == 0 {
+		return nil
+	}
+	return s.rates.Upsert(ctx, recordsOf(desc))
+}
+
+func recordsOf(desc request.Descriptor) []store.Record {
+	source := sourceOf(desc)
+	records := make([]store.Record, 0, len(desc.Payload.Rates))
+	for _, rate := range desc.Payload.Rates {
+		records = append(records, store.Record{
+			Currency: desc.Payload.Name,
+			Source:   source,
+			Date:     rate.Date,
+			Value:    rate.Value,
+		})
+	}
+	return records
+}
+
+func sourceOf(desc request.Descriptor) string {
+	if desc.Target != "" {
+		return desc.Target
+	}
+	u, err := url.Parse(desc.URL)
+	if err != nil {
+		return desc.URL
+	}
+	return u.Host
+}
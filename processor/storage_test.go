@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koenno/currency-price-monitor/store"
+	"github.com/koenno/currency-price-monitor/store/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldStoreRatesUnderTargetSource(t *testing.T) {
+	// given
+	storeMock := mocks.NewRateStore(t)
+	sut := NewStorage(storeMock)
+	desc := newRatesDescriptor("EUR", newRate("2023-10-02", 4.60), newRate("2023-10-03", 4.61))
+	desc.Target = "nbp-eur"
+	storeMock.EXPECT().Upsert(context.Background(), []store.Record{
+		{Currency: "EUR", Source: "nbp-eur", Date: newDate("2023-10-02"), Value: 4.60},
+		{Currency: "EUR", Source: "nbp-eur", Date: newDate("2023-10-03"), Value: 4.61},
+	}).Return(nil).Once()
+
+	// when
+	err := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldUseHostAsSourceWithoutTarget(t *testing.T) {
+	// given
+	storeMock := mocks.NewRateStore(t)
+	sut := NewStorage(storeMock)
+	desc := newRatesDescriptor("EUR", newRate("2023-10-03", 4.61))
+	desc.URL = "http://api.nbp.pl/api/exchangerates/rates/a/eur"
+	storeMock.EXPECT().Upsert(context.Background(), []store.Record{
+		{Currency: "EUR", Source: "api.nbp.pl", Date: newDate("2023-10-03"), Value: 4.61},
+	}).Return(nil).Once()
+
+	// when
+	err := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldSkipDescriptorsWithoutRates(t *testing.T) {
+	// given
+	sut := NewStorage(mocks.NewRateStore(t))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR"))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestShouldLogDescriptorsWithoutRates(t *testing.T) {
+	// given
+	logMock := mocks.NewDescriptorLog(t)
+	sut := NewStorage(mocks.NewRateStore(t), WithDescriptorLog(logMock))
+	desc := newRatesDescriptor("EUR")
+	logMock.EXPECT().AppendDescriptor(context.Background(), desc).Return(nil).Once()
+
+	// when
+	err := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+}
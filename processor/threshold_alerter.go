@@ -0,0 +1,232 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	thresholdAlertName = "threshold"
+)
+
+type BandState string
+
+const (
+	BandUnknown BandState = ""
+	BandInside  BandState = "inside"
+	BandAbove   BandState = "above"
+	BandBelow   BandState = "below"
+)
+
+type Threshold struct {
+	Currency string
+	Band     ClosedInterval
+	Margin   float64
+	MinDwell time.Duration
+}
+
+type bandTracker struct {
+	State          BandState
+	Candidate      BandState
+	CandidateSince time.Time
+	LastDate       time.Time
+}
+
+type thresholdOptions struct {
+	store state.Store
+}
+
+type ThresholdOption func(*thresholdOptions)
+
+func WithThresholdStore(store state.Store) ThresholdOption {
+	return func(o *thresholdOptions) {
+		o.store = store
+	}
+}
+
+type ThresholdAlerter struct {
+	sink      alert.Sink
+	threshold Threshold
+	options   thresholdOptions
+
+	mtx      sync.Mutex
+	trackers map[string]*bandTracker
+}
+
+func NewThresholdAlerter(sink alert.Sink, threshold Threshold, opts ...ThresholdOption) *ThresholdAlerter {
+	cfg := thresholdOptions{}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &ThresholdAlerter{
+		sink:      sink,
+		threshold: threshold,
+		options:   cfg,
+		trackers:  make(map[string]*bandTracker),
+	}
+}
+
+func (a *ThresholdAlerter) Process(ctx context.Context, desc request.Descriptor) error {
+	if a.threshold.Currency != "" && desc.Payload.Name != a.threshold.Currency {
+		return nil
+	}
+	events, err := a.evaluate(desc.Payload)
+	if len(events) == 0 {
+		return err
+	}
+	return errors.Join(err, a.sink.Notify(ctx, events))
+}
+
+func (a *ThresholdAlerter) State(currency string) BandState {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	tracker, ok := a.trackers[currency]
+	if !ok {
+		return BandUnknown
+	}
+	return tracker.State
+}
+
+func (a *ThresholdAlerter) evaluate(payload request.Currency) ([]alert.Event, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	tracker, err := a.tracker(payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	lastDate := tracker.LastDate
+	rates := sortedRates(payload.Rates)
+	if lastDate.IsZero() && len(rates) > 0 {
+		// the history of the first descriptor only sets the initial state,
+		// so a fresh start does not alert on transitions long gone
+		latest := rates[len(rates)-1]
+		tracker.State = a.classify(BandUnknown, latest.Value)
+		tracker.LastDate = latest.Date
+		rates = nil
+	}
+
+	var events []alert.Event
+	for _, rate := range rates {
+		if !rate.Date.After(tracker.LastDate) {
+			continue
+		}
+		tracker.LastDate = rate.Date
+
+		target := a.classify(tracker.State, rate.Value)
+		if target == tracker.State {
+			tracker.Candidate = BandUnknown
+			continue
+		}
+		if tracker.Candidate != target {
+			tracker.Candidate = target
+			tracker.CandidateSince = rate.Date
+		}
+		if rate.Date.Sub(tracker.CandidateSince) < a.threshold.MinDwell {
+			continue
+		}
+
+		previous := tracker.State
+		tracker.State = target
+		tracker.Candidate = BandUnknown
+		if previous == BandUnknown && target == BandInside {
+			continue
+		}
+		events = append(events, a.newEvent(payload.Name, rate, previous, target))
+	}
+	if a.options.store == nil || !tracker.LastDate.After(lastDate) {
+		return events, nil
+	}
+	return events, a.options.store.Save(thresholdKey(payload.Name), tracker)
+}
+
+// tracker must be called with mtx held.
+func (a *ThresholdAlerter) tracker(currency string) (*bandTracker, error) {
+	if tracker, ok := a.trackers[currency]; ok {
+		return tracker, nil
+	}
+	tracker := &bandTracker{}
+	if a.options.store != nil {
+		err := a.options.store.Load(thresholdKey(currency), tracker)
+		if err != nil && !errors.Is(err, state.ErrNotFound) {
+			return nil, err
+		}
+	}
+	a.trackers[currency] = tracker
+	return tracker, nil
+}
+
+func thresholdKey(currency string) string {
+	return fmt.Sprintf("%s/%s", thresholdAlertName, currency)
+}
+
+func (a *ThresholdAlerter) classify(current BandState, value float64) BandState {
+	band := a.threshold.Band
+	margin := a.threshold.Margin
+	switch current {
+	case BandAbove:
+		if value > band.B-margin {
+			return BandAbove
+		}
+	case BandBelow:
+		if value < band.A+margin {
+			return BandBelow
+		}
+	case BandUnknown:
+		margin = 0
+	}
+	switch {
+	case value > band.B+margin:
+		return BandAbove
+	case value < band.A-margin:
+		return BandBelow
+	default:
+		return BandInside
+	}
+}
+
+func (a *ThresholdAlerter) newEvent(currency string, rate request.Rate, previous, current BandState) alert.Event {
+	band := a.threshold.Band
+	event := alert.Event{
+		Key:      fmt.Sprintf("%s/%s", thresholdAlertName, currency),
+		Name:     thresholdAlertName,
+		Currency: currency,
+		Rate:     rate,
+		Severity: alert.SeverityWarning,
+		Time:     time.Now(),
+		Labels: map[string]string{
+			"state":    string(current),
+			"previous": string(previous),
+		},
+		Values: map[string]float64{
+			"value": rate.Value,
+			"lower": band.A,
+			"upper": band.B,
+		},
+	}
+	if current == BandInside {
+		event.Severity = alert.SeverityInfo
+		event.Resolved = true
+		event.Message = fmt.Sprintf("%s rate %v returned into band [%v, %v]", currency, rate.Value, band.A, band.B)
+		return event
+	}
+	event.Message = fmt.Sprintf("%s rate %v moved %s band [%v, %v]", currency, rate.Value, current, band.A, band.B)
+	return event
+}
+
+func sortedRates(rates []request.Rate) []request.Rate {
+	sorted := make([]request.Rate, len(rates))
+	copy(sorted, rates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return sorted
+}
@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShouldNotifyOnlyOnBandTransitions(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewThresholdAlerter(sinkMock, Threshold{
+		Currency: "EUR",
+		Band:     ClosedInterval{A: 4.5, B: 4.7},
+	})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	})
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.6))))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.6),
+		newRate("2023-10-03", 4.8),
+		newRate("2023-10-04", 4.9),
+		newRate("2023-10-05", 4.6),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 2)
+	assert.Equal(t, "above", notified[0].Labels["state"])
+	assert.False(t, notified[0].Resolved)
+	assert.Equal(t, "inside", notified[1].Labels["state"])
+	assert.True(t, notified[1].Resolved)
+	assert.Equal(t, BandInside, sut.State("EUR"))
+}
+
+func TestShouldNotRepeatAlertForAlreadyProcessedRates(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewThresholdAlerter(sinkMock, Threshold{
+		Band: ClosedInterval{A: 4.5, B: 4.7},
+	})
+	desc := newRatesDescriptor("EUR", newRate("2023-10-03", 4.8))
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.6))))
+
+	// when
+	errFirst := sut.Process(context.Background(), desc)
+	errSecond := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Equal(t, BandAbove, sut.State("EUR"))
+}
+
+func TestShouldApplyHysteresisMargin(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewThresholdAlerter(sinkMock, Threshold{
+		Band:   ClosedInterval{A: 4.5, B: 4.7},
+		Margin: 0.05,
+	})
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.6))))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.6),
+		newRate("2023-10-03", 4.72),
+		newRate("2023-10-04", 4.76),
+		newRate("2023-10-05", 4.68),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, BandAbove, sut.State("EUR"))
+}
+
+func TestShouldWaitForMinimumDwellTime(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewThresholdAlerter(sinkMock, Threshold{
+		Band:     ClosedInterval{A: 4.5, B: 4.7},
+		MinDwell: 48 * time.Hour,
+	})
+	var notified []alert.Event
+	sinkMock.EXPECT().Notify(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, events []alert.Event) error {
+		notified = append(notified, events...)
+		return nil
+	})
+
+	assert.NoError(t, sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.6))))
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.6),
+		newRate("2023-10-03", 4.4),
+		newRate("2023-10-04", 4.6),
+		newRate("2023-10-05", 4.4),
+		newRate("2023-10-06", 4.4),
+		newRate("2023-10-07", 4.4),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, newDate("2023-10-07"), notified[0].Rate.Date)
+	assert.Equal(t, BandBelow, sut.State("EUR"))
+}
+
+func TestShouldResumeBandStateAfterRestart(t *testing.T) {
+	// given
+	store := state.NewMemoryStore()
+	threshold := Threshold{Band: ClosedInterval{A: 4.5, B: 4.7}}
+	desc := newRatesDescriptor("EUR", newRate("2023-10-02", 4.6), newRate("2023-10-03", 4.8))
+	firstSink := mocks.NewSink(t)
+	firstSink.EXPECT().Notify(mock.Anything, mock.Anything).Return(nil).Once()
+	first := NewThresholdAlerter(firstSink, threshold, WithThresholdStore(store))
+	assert.NoError(t, first.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-01", 4.6))))
+	assert.NoError(t, first.Process(context.Background(), desc))
+
+	// when
+	second := NewThresholdAlerter(mocks.NewSink(t), threshold, WithThresholdStore(store))
+	err := second.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, BandAbove, second.State("EUR"))
+}
+
+func TestShouldOnlySetInitialStateFromFirstDescriptor(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewThresholdAlerter(sinkMock, Threshold{
+		Band: ClosedInterval{A: 4.5, B: 4.7},
+	})
+
+	// when
+	err := sut.Process(context.Background(), newRatesDescriptor("EUR",
+		newRate("2023-10-02", 4.6),
+		newRate("2023-10-03", 4.4),
+		newRate("2023-10-04", 4.8),
+	))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, BandAbove, sut.State("EUR"))
+}
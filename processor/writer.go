@@ -3,21 +3,56 @@ package processor
 import (
 	"context"
 	"io"
+	"sync"
 
 	"github.com/koenno/currency-price-monitor/request"
 )
 
+type writerOptions struct {
+	formatter Formatter
+	predicate Predicate
+}
+
+type WriterOption func(*writerOptions)
+
+func WithFormatter(formatter Formatter) WriterOption {
+	return func(o *writerOptions) {
+		o.formatter = formatter
+	}
+}
+
+func WithFilter(predicate Predicate) WriterOption {
+	return func(o *writerOptions) {
+		o.predicate = predicate
+	}
+}
+
 type Writer struct {
-	out io.Writer
+	mtx       sync.Mutex
+	out       io.Writer
+	formatter Formatter
+	predicate Predicate
 }
 
-func NewWriter[T any](out io.Writer) Writer {
-	return Writer{
-		out: out,
+func NewWriter(out io.Writer, opts ...WriterOption) *Writer {
+	cfg := writerOptions{
+		formatter: TextFormatter{},
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &Writer{
+		out:       out,
+		formatter: cfg.formatter,
+		predicate: cfg.predicate,
 	}
 }
 
-func (w Writer) Process(ctx context.Context, desc request.Descriptor) error {
-	_, err := desc.WriteTo(w.out)
-	return err
+func (w *Writer) Process(ctx context.Context, desc request.Descriptor) error {
+	if w.predicate != nil && !w.predicate(desc) {
+		return nil
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.formatter.Format(w.out, desc)
 }
@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoggedDescriptor() request.Descriptor {
+	desc := newRatesDescriptor("EUR", newRate("2023-10-03", 4.61), newRate("2023-10-02", 4.6))
+	desc.Target = "nbp-eur"
+	desc.URL = "http://api.nbp.pl/api?a=1&b=2"
+	desc.Time = time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)
+	desc.Valid = true
+	desc.Duration = 1500 * time.Microsecond
+	desc.StatusCode = 200
+	desc.ResponseSize = 120
+	desc.Attempts = 1
+	return desc
+}
+
+func TestShouldWriteInTextFormatByDefault(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	desc := newLoggedDescriptor()
+	expected := &bytes.Buffer{}
+	desc.WriteTo(expected)
+	sut := NewWriter(out)
+
+	// when
+	err := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), out.String())
+}
+
+func TestShouldWriteOneJSONDescriptorPerLine(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut := NewWriter(out, WithFormatter(JSONFormatter{}))
+	first := newLoggedDescriptor()
+	second := request.Descriptor{ID: "2", Error: "timeout"}
+
+	// when
+	errFirst := sut.Process(context.Background(), first)
+	errSecond := sut.Process(context.Background(), second)
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	var actual []request.Descriptor
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var desc request.Descriptor
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &desc))
+		actual = append(actual, desc)
+	}
+	assert.Equal(t, []request.Descriptor{first, second}, actual)
+}
+
+func TestShouldWriteInLogfmtFormat(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut := NewWriter(out, WithFormatter(LogfmtFormatter{}))
+	desc := newLoggedDescriptor()
+	desc.ErrorKind = request.ErrorDecode
+	desc.Error = `field "rates"`
+
+	// when
+	err := sut.Process(context.Background(), desc)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, `id=1 target=nbp-eur url="http://api.nbp.pl/api?a=1&b=2" time=2023-10-03T12:00:00Z `+
+		`valid_status_code=false json=false valid=true duration=1.5ms status_code=200 response_size=120 attempts=1 currency=EUR rates=2 `+
+		`latest_date=2023-10-03 latest_value=4.61 error_kind=decode error="field \"rates\""`+"\n", out.String())
+}
+
+func TestShouldWriteInCSVFormatWithHeaderOnce(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut := NewWriter(out, WithFormatter(NewCSVFormatter(true)))
+
+	// when
+	errFirst := sut.Process(context.Background(), newLoggedDescriptor())
+	errSecond := sut.Process(context.Background(), request.Descriptor{ID: "2", ErrorKind: request.ErrorTransport, Error: "timeout, retrying"})
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Equal(t, "id,target,url,time,valid_status_code,json,valid,duration_ms,status_code,response_size,attempts,currency,rates,latest_date,latest_value,backfill,error_kind,error\n"+
+		"1,nbp-eur,http://api.nbp.pl/api?a=1&b=2,2023-10-03T12:00:00Z,false,false,true,1.5,200,120,1,EUR,2,2023-10-03,4.61,false,,\n"+
+		"2,,,0001-01-01T00:00:00Z,false,false,false,0,0,0,0,,0,,,false,transport,\"timeout, retrying\"\n", out.String())
+}
+
+func TestShouldWriteWithTemplate(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	formatter, err := NewTemplateFormatter(`{{.Payload.Name}} {{len .Payload.Rates}} rates in {{.Duration}}`)
+	assert.NoError(t, err)
+	sut := NewWriter(out, WithFormatter(formatter))
+
+	// when
+	err = sut.Process(context.Background(), newLoggedDescriptor())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR 2 rates in 1.5ms\n", out.String())
+}
+
+func TestShouldRejectInvalidTemplate(t *testing.T) {
+	// when
+	_, err := NewTemplateFormatter(`{{.Payload.Name`)
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestShouldWriteOnlyFilteredDescriptors(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	sut := NewWriter(out, WithFormatter(LogfmtFormatter{}), WithFilter(Failed))
+	failed := request.Descriptor{ID: "2", ErrorKind: request.ErrorTransport}
+
+	// when
+	errSucceeded := sut.Process(context.Background(), newLoggedDescriptor())
+	errFailed := sut.Process(context.Background(), failed)
+
+	// then
+	assert.NoError(t, errSucceeded)
+	assert.NoError(t, errFailed)
+	assert.Contains(t, out.String(), "id=2 ")
+	assert.NotContains(t, out.String(), "id=1 ")
+}
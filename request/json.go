@@ -0,0 +1,170 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type jsonRate struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonRate{
+		Date:  r.Date.Format(time.DateOnly),
+		Value: r.Value,
+	})
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var raw jsonRate
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	date, err := time.Parse(time.DateOnly, raw.Date)
+	if err != nil {
+		return fmt.Errorf("invalid rate date %q: %v", raw.Date, err)
+	}
+	r.Date = date
+	r.Value = raw.Value
+	return nil
+}
+
+type jsonCurrency struct {
+	Name  string `json:"currency"`
+	Rates []Rate `json:"rates"`
+}
+
+func (c Currency) MarshalJSON() ([]byte, error) {
+	rates := c.Rates
+	if rates == nil {
+		rates = []Rate{}
+	}
+	return json.Marshal(jsonCurrency{
+		Name:  c.Name,
+		Rates: rates,
+	})
+}
+
+func (c *Currency) UnmarshalJSON(data []byte) error {
+	var raw jsonCurrency
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c.Name = raw.Name
+	c.Rates = nil
+	if len(raw.Rates) > 0 {
+		c.Rates = raw.Rates
+	}
+	return nil
+}
+
+type jsonSample struct {
+	Probes       int     `json:"probes"`
+	Succeeded    int     `json:"succeeded"`
+	Agreeing     int     `json:"agreeing"`
+	Quorum       bool    `json:"quorum"`
+	Disagreement bool    `json:"disagreement"`
+	LatencyP50   float64 `json:"latency_p50_ms"`
+	LatencyP90   float64 `json:"latency_p90_ms"`
+	LatencyP99   float64 `json:"latency_p99_ms"`
+}
+
+type jsonDescriptor struct {
+	ID              string      `json:"id"`
+	Target          string      `json:"target,omitempty"`
+	URL             string      `json:"url"`
+	Time            time.Time   `json:"time"`
+	ValidStatusCode bool        `json:"valid_status_code"`
+	JSON            bool        `json:"json"`
+	Valid           bool        `json:"valid"`
+	Duration        float64     `json:"duration_ms"`
+	StatusCode      int         `json:"status_code,omitempty"`
+	ResponseSize    int64       `json:"response_size,omitempty"`
+	Attempts        int         `json:"attempts,omitempty"`
+	Payload         Currency    `json:"payload"`
+	Sample          *jsonSample `json:"sample,omitempty"`
+	Backfill        bool        `json:"backfill,omitempty"`
+	ErrorKind       ErrorKind   `json:"error_kind,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+func (d Descriptor) MarshalJSON() ([]byte, error) {
+	raw := jsonDescriptor{
+		ID:              d.ID,
+		Target:          d.Target,
+		URL:             d.URL,
+		Time:            d.Time,
+		ValidStatusCode: d.ValidStatusCode,
+		JSON:            d.JSON,
+		Valid:           d.Valid,
+		Duration:        milliseconds(d.Duration),
+		StatusCode:      d.StatusCode,
+		ResponseSize:    d.ResponseSize,
+		Attempts:        d.Attempts,
+		Payload:         d.Payload,
+		Backfill:        d.Backfill,
+		ErrorKind:       d.ErrorKind,
+		Error:           d.Error,
+	}
+	if d.Sample.Probes > 0 {
+		raw.Sample = &jsonSample{
+			Probes:       d.Sample.Probes,
+			Succeeded:    d.Sample.Succeeded,
+			Agreeing:     d.Sample.Agreeing,
+			Quorum:       d.Sample.Quorum,
+			Disagreement: d.Sample.Disagreement,
+			LatencyP50:   milliseconds(d.Sample.LatencyP50),
+			LatencyP90:   milliseconds(d.Sample.LatencyP90),
+			LatencyP99:   milliseconds(d.Sample.LatencyP99),
+		}
+	}
+	return json.Marshal(raw)
+}
+
+func (d *Descriptor) UnmarshalJSON(data []byte) error {
+	var raw jsonDescriptor
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*d = Descriptor{
+		ID:              raw.ID,
+		Target:          raw.Target,
+		URL:             raw.URL,
+		Time:            raw.Time,
+		ValidStatusCode: raw.ValidStatusCode,
+		JSON:            raw.JSON,
+		Valid:           raw.Valid,
+		Duration:        fromMilliseconds(raw.Duration),
+		StatusCode:      raw.StatusCode,
+		ResponseSize:    raw.ResponseSize,
+		Attempts:        raw.Attempts,
+		Payload:         raw.Payload,
+		Backfill:        raw.Backfill,
+		ErrorKind:       raw.ErrorKind,
+		Error:           raw.Error,
+	}
+	if raw.Sample != nil {
+		d.Sample = Sample{
+			Probes:       raw.Sample.Probes,
+			Succeeded:    raw.Sample.Succeeded,
+			Agreeing:     raw.Sample.Agreeing,
+			Quorum:       raw.Sample.Quorum,
+			Disagreement: raw.Sample.Disagreement,
+			LatencyP50:   fromMilliseconds(raw.Sample.LatencyP50),
+			LatencyP90:   fromMilliseconds(raw.Sample.LatencyP90),
+			LatencyP99:   fromMilliseconds(raw.Sample.LatencyP99),
+		}
+	}
+	return nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func fromMilliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
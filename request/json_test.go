@@ -0,0 +1,99 @@
+package request
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldMarshalDescriptorWithPayload(t *testing.T) {
+	// given
+	desc := Descriptor{
+		ID:              "1",
+		Target:          "nbp-eur",
+		URL:             "http://api.nbp.pl/api/exchangerates/rates/a/eur",
+		Time:            time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC),
+		ValidStatusCode: true,
+		JSON:            true,
+		Valid:           true,
+		Duration:        1500 * time.Microsecond,
+		Payload: Currency{
+			Name:  "EUR",
+			Rates: []Rate{{Date: time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC), Value: 4.61}},
+		},
+	}
+
+	// when
+	actual, err := json.Marshal(desc)
+
+	// then
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "1",
+		"target": "nbp-eur",
+		"url": "http://api.nbp.pl/api/exchangerates/rates/a/eur",
+		"time": "2023-10-03T12:00:00Z",
+		"valid_status_code": true,
+		"json": true,
+		"valid": true,
+		"duration_ms": 1.5,
+		"payload": {"currency": "EUR", "rates": [{"date": "2023-10-03", "value": 4.61}]}
+	}`, string(actual))
+}
+
+func TestShouldMarshalSampleAndError(t *testing.T) {
+	// given
+	desc := Descriptor{
+		ID: "1",
+		Sample: Sample{
+			Probes:     3,
+			Succeeded:  0,
+			LatencyP50: 20 * time.Millisecond,
+		},
+		Error: "connection refused",
+	}
+
+	// when
+	actual, err := json.Marshal(desc)
+
+	// then
+	assert.NoError(t, err)
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal(actual, &fields))
+	assert.Equal(t, "connection refused", fields["error"])
+	assert.Equal(t, map[string]any{"currency": "", "rates": []any{}}, fields["payload"])
+	sample := fields["sample"].(map[string]any)
+	assert.Equal(t, 3.0, sample["probes"])
+	assert.Equal(t, 20.0, sample["latency_p50_ms"])
+}
+
+func TestShouldUnmarshalMarshalledDescriptor(t *testing.T) {
+	// given
+	expected := Descriptor{
+		ID:       "1",
+		Target:   "nbp-eur",
+		URL:      "http://api.nbp.pl/api/exchangerates/rates/a/eur",
+		Time:     time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC),
+		Valid:    true,
+		Duration: 250 * time.Millisecond,
+		Payload: Currency{
+			Name:  "EUR",
+			Rates: []Rate{{Date: time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC), Value: 4.61}},
+		},
+		Sample:   Sample{Probes: 2, Succeeded: 2, Agreeing: 2, Quorum: true, LatencyP99: 30 * time.Millisecond},
+		Backfill: true,
+		Error:    "partial failure",
+	}
+	content, err := json.Marshal(expected)
+	assert.NoError(t, err)
+
+	// when
+	var actual Descriptor
+	err = json.Unmarshal(content, &actual)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -22,20 +22,68 @@ func (r Rate) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
+type Sample struct {
+	Probes       int
+	Succeeded    int
+	Agreeing     int
+	Quorum       bool
+	Disagreement bool
+	LatencyP50   time.Duration
+	LatencyP90   time.Duration
+	LatencyP99   time.Duration
+}
+
+type ErrorKind string
+
+const (
+	ErrorTransport  ErrorKind = "transport"
+	ErrorHTTPStatus ErrorKind = "http_status"
+	ErrorPayload    ErrorKind = "payload"
+	ErrorDecode     ErrorKind = "decode"
+)
+
 type Descriptor struct {
 	ID              string
+	Target          string
 	URL             string
 	Time            time.Time
 	ValidStatusCode bool
 	JSON            bool
 	Valid           bool
 	Duration        time.Duration
+	StatusCode      int
+	ResponseSize    int64
+	Attempts        int
 	Payload         Currency
+	Sample          Sample
+	Backfill        bool
+	ErrorKind       ErrorKind
+	Error           string
+}
+
+// Failed reports whether the request behind the descriptor did not succeed,
+// either classified by the client or reported by the requester.
+func (d Descriptor) Failed() bool {
+	return d.ErrorKind != "" || d.Error != ""
 }
 
 func (d Descriptor) WriteTo(w io.Writer) (int64, error) {
-	str := fmt.Sprintf("request id=%v url=%v time=%v validStatusCode=%v json=%v validJson=%v duration=%v\n",
-		d.ID, d.URL, d.Time, d.ValidStatusCode, d.JSON, d.Valid, d.Duration)
-	n, err := io.WriteString(w, str)
+	str := fmt.Sprintf("request id=%v target=%v url=%v time=%v validStatusCode=%v json=%v validJson=%v duration=%v statusCode=%v size=%v attempts=%v",
+		d.ID, d.Target, d.URL, d.Time, d.ValidStatusCode, d.JSON, d.Valid, d.Duration, d.StatusCode, d.ResponseSize, d.Attempts)
+	if d.Sample.Probes > 0 {
+		str += fmt.Sprintf(" probes=%v succeeded=%v agreeing=%v quorum=%v disagreement=%v p50=%v p90=%v p99=%v",
+			d.Sample.Probes, d.Sample.Succeeded, d.Sample.Agreeing, d.Sample.Quorum, d.Sample.Disagreement,
+			d.Sample.LatencyP50, d.Sample.LatencyP90, d.Sample.LatencyP99)
+	}
+	if d.Backfill {
+		str += " backfill=true"
+	}
+	if d.ErrorKind != "" {
+		str += fmt.Sprintf(" errorKind=%v", d.ErrorKind)
+	}
+	if d.Error != "" {
+		str += fmt.Sprintf(" error=%q", d.Error)
+	}
+	n, err := io.WriteString(w, str+"\n")
 	return int64(n), err
 }
@@ -0,0 +1,360 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	backupTimeLayout  = "20060102T150405.000"
+	compressedSuffix  = ".gz"
+	defaultMaxBackups = 7
+)
+
+var (
+	ErrClosed = errors.New("rotating writer closed")
+)
+
+type options struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	compress   bool
+	perm       os.FileMode
+	header     []byte
+}
+
+type Option func(*options)
+
+// WithMaxSize rotates the file before a write would make it exceed size bytes.
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+// WithInterval rotates the file once it has been written to for longer than interval.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files; 0 keeps all of them.
+func WithMaxBackups(n int) Option {
+	return func(o *options) {
+		o.maxBackups = n
+	}
+}
+
+func WithCompression(compress bool) Option {
+	return func(o *options) {
+		o.compress = compress
+	}
+}
+
+// WithHeader starts every new file with header, such as the column names of
+// a CSV log.
+func WithHeader(header []byte) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+func WithPermissions(perm os.FileMode) Option {
+	return func(o *options) {
+		o.perm = perm
+	}
+}
+
+// Writer appends to a file and rotates it by size or age. Rotated files are
+// renamed with a timestamp suffix, optionally gzipped in the background and
+// pruned to the configured number of backups.
+type Writer struct {
+	path    string
+	options options
+	now     func() time.Time
+	rename  func(oldpath, newpath string) error
+
+	mtx    sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	post sync.Mutex
+	wg   sync.WaitGroup
+}
+
+func Open(path string, opts ...Option) (*Writer, error) {
+	cfg := options{
+		maxBackups: defaultMaxBackups,
+		perm:       0644,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.maxSize < 0 || cfg.interval < 0 || cfg.maxBackups < 0 {
+		return nil, fmt.Errorf("invalid rotation of %s: negative limit", path)
+	}
+	w := &Writer{
+		path:    path,
+		options: cfg,
+		now:     time.Now,
+		rename:  os.Rename,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.due(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			// a failed rotation keeps writing to the current file when possible
+			if w.file == nil {
+				return 0, err
+			}
+			n, errWrite := w.write(p)
+			return n, errors.Join(err, errWrite)
+		}
+	}
+	return w.write(p)
+}
+
+func (w *Writer) write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Size returns the number of bytes in the current file.
+func (w *Writer) Size() int64 {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.size
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (w *Writer) Rotate() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file at the same path, so the writer follows
+// a file moved away by an external tool such as logrotate.
+func (w *Writer) Reopen() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the file and waits for pending compressions.
+func (w *Writer) Close() error {
+	w.mtx.Lock()
+	if w.closed {
+		w.mtx.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFile()
+	w.mtx.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *Writer) due(next int64) bool {
+	if w.options.maxSize > 0 && w.size > int64(len(w.options.header)) && w.size+next > w.options.maxSize {
+		return true
+	}
+	return w.options.interval > 0 && w.now().Sub(w.opened) >= w.options.interval
+}
+
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return fmt.Errorf("unable to create directory of %s: %v", w.path, err)
+	}
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, w.options.perm)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %v", w.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to stat %s: %v", w.path, err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.now()
+	if w.size == 0 && len(w.options.header) > 0 {
+		if _, err := w.write(w.options.header); err != nil {
+			return fmt.Errorf("unable to write header of %s: %v", w.path, err)
+		}
+	}
+	return nil
+}
+
+// closeFile leaves the writer without a file, so that the next write or
+// rotation opens it again even if closing failed.
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("unable to close %s: %v", w.path, err)
+	}
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	backup := w.backupName()
+	if err := w.rename(w.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(fmt.Errorf("unable to rotate %s: %v", w.path, err), w.open())
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.post.Lock()
+		defer w.post.Unlock()
+		if w.options.compress {
+			if err := compress(backup, w.options.perm); err != nil {
+				slog.Error("failed to compress rotated file", "path", backup, "error", err)
+			}
+		}
+		if err := w.prune(); err != nil {
+			slog.Error("failed to remove old rotated files", "path", w.path, "error", err)
+		}
+	}()
+	return nil
+}
+
+func (w *Writer) backupName() string {
+	name := w.path + "." + w.now().Format(backupTimeLayout)
+	candidate := name
+	for i := 1; exists(candidate) || exists(candidate+compressedSuffix); i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
+
+func (w *Writer) prune() error {
+	if w.options.maxBackups == 0 {
+		return nil
+	}
+	backups, err := Backups(w.path)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= w.options.maxBackups {
+		return nil
+	}
+	var errs []error
+	for _, b := range backups[:len(backups)-w.options.maxBackups] {
+		if err := os.Remove(b); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Backups lists rotated files of path, oldest first.
+func Backups(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list rotated files of %s: %v", path, err)
+	}
+	prefix := filepath.Base(path) + "."
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressedSuffix)
+		if len(stamp) < len(backupTimeLayout) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, stamp[:len(backupTimeLayout)]); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(path), name))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func compress(path string, perm os.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressedSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
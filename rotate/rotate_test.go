@@ -0,0 +1,256 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func openWriter(t *testing.T, path string, opts ...Option) (*Writer, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)}
+	w, err := Open(path, opts...)
+	assert.NoError(t, err)
+	w.now = clock.Now
+	w.opened = clock.Now()
+	t.Cleanup(func() { w.Close() })
+	return w, clock
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestShouldRotateWhenWriteExceedsMaxSize(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, clock := openWriter(t, path, WithMaxSize(10))
+
+	// when
+	_, errFirst := sut.Write([]byte("12345678\n"))
+	clock.Advance(time.Second)
+	_, errSecond := sut.Write([]byte("abc\n"))
+	errClose := sut.Close()
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errClose)
+	backups, err := Backups(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path + ".20231003T120001.000"}, backups)
+	assert.Equal(t, "12345678\n", readFile(t, backups[0]))
+	assert.Equal(t, "abc\n", readFile(t, path))
+}
+
+func TestShouldStartEveryFileWithHeader(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.csv")
+	sut, clock := openWriter(t, path, WithMaxSize(20), WithHeader([]byte("a,b\n")))
+
+	// when
+	_, errFirst := sut.Write([]byte("1,2\n3,4\n5,6\n7,8\n"))
+	clock.Advance(time.Second)
+	_, errSecond := sut.Write([]byte("9,0\n"))
+	sut.Close()
+	reopened, _ := openWriter(t, path, WithHeader([]byte("a,b\n")))
+	_, errThird := reopened.Write([]byte("1,1\n"))
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errThird)
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Len(t, backups, 1)
+	assert.Equal(t, "a,b\n1,2\n3,4\n5,6\n7,8\n", readFile(t, backups[0]))
+	assert.Equal(t, "a,b\n9,0\n1,1\n", readFile(t, path))
+}
+
+func TestShouldRotateWhenIntervalElapses(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, clock := openWriter(t, path, WithInterval(time.Hour))
+
+	// when
+	sut.Write([]byte("first\n"))
+	clock.Advance(59 * time.Minute)
+	sut.Write([]byte("second\n"))
+	clock.Advance(time.Minute)
+	sut.Write([]byte("third\n"))
+	sut.Close()
+
+	// then
+	backups, err := Backups(path)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, "first\nsecond\n", readFile(t, backups[0]))
+	assert.Equal(t, "third\n", readFile(t, path))
+}
+
+func TestShouldKeepOnlyMaxBackups(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, clock := openWriter(t, path, WithMaxBackups(2))
+
+	// when
+	for i := 1; i <= 4; i++ {
+		sut.Write([]byte(fmt.Sprintf("%d\n", i)))
+		clock.Advance(time.Second)
+		assert.NoError(t, sut.Rotate())
+	}
+	sut.Close()
+
+	// then
+	backups, err := Backups(path)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	assert.Equal(t, "3\n", readFile(t, backups[0]))
+	assert.Equal(t, "4\n", readFile(t, backups[1]))
+}
+
+func TestShouldCompressRotatedFiles(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, _ := openWriter(t, path, WithCompression(true))
+	sut.Write([]byte("compressed content\n"))
+
+	// when
+	err := sut.Rotate()
+	sut.Close()
+
+	// then
+	assert.NoError(t, err)
+	backups, errList := Backups(path)
+	assert.NoError(t, errList)
+	assert.Len(t, backups, 1)
+	assert.True(t, strings.HasSuffix(backups[0], ".gz"))
+	f, _ := os.Open(backups[0])
+	defer f.Close()
+	zr, errGzip := gzip.NewReader(f)
+	assert.NoError(t, errGzip)
+	content, _ := io.ReadAll(zr)
+	assert.Equal(t, "compressed content\n", string(content))
+}
+
+func TestShouldRecoverWhenRotationFails(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, clock := openWriter(t, path, WithMaxSize(10))
+	sut.rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	sut.Write([]byte("12345678\n"))
+
+	// when
+	_, errFailed := sut.Write([]byte("abc\n"))
+	errReopen := sut.Reopen()
+	sut.rename = os.Rename
+	clock.Advance(time.Second)
+	_, errRotated := sut.Write([]byte("def\n"))
+
+	// then
+	assert.ErrorContains(t, errFailed, "unable to rotate")
+	assert.NoError(t, errReopen)
+	assert.NoError(t, errRotated)
+	backups, err := Backups(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path + ".20231003T120001.000"}, backups)
+	assert.Equal(t, "12345678\nabc\n", readFile(t, backups[0]))
+	assert.Equal(t, "def\n", readFile(t, path))
+}
+
+func TestShouldReopenFileMovedAway(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.txt")
+	sut, _ := openWriter(t, path)
+	sut.Write([]byte("before\n"))
+	assert.NoError(t, os.Rename(path, filepath.Join(dir, "moved.txt")))
+
+	// when
+	err := sut.Reopen()
+	sut.Write([]byte("after\n"))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "before\n", readFile(t, filepath.Join(dir, "moved.txt")))
+	assert.Equal(t, "after\n", readFile(t, path))
+}
+
+func TestShouldWriteConcurrentlyWithoutInterleaving(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "log.txt")
+	sut, _ := openWriter(t, path, WithMaxSize(1024), WithMaxBackups(0))
+	line := strings.Repeat("x", 99) + "\n"
+
+	// when
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sut.Write([]byte(line))
+			}
+		}()
+	}
+	wg.Wait()
+	sut.Close()
+
+	// then
+	backups, err := Backups(path)
+	assert.NoError(t, err)
+	total := 0
+	for _, f := range append(backups, path) {
+		content := readFile(t, f)
+		assert.LessOrEqual(t, len(content), 1024)
+		for _, l := range strings.SplitAfter(content, "\n") {
+			if l != "" {
+				assert.Equal(t, line, l)
+				total++
+			}
+		}
+	}
+	assert.Equal(t, 400, total)
+}
+
+func TestShouldRejectWritesAfterClose(t *testing.T) {
+	// given
+	sut, _ := openWriter(t, filepath.Join(t.TempDir(), "log.txt"))
+	sut.Close()
+
+	// when
+	_, err := sut.Write([]byte("late\n"))
+
+	// then
+	assert.ErrorIs(t, err, ErrClosed)
+}
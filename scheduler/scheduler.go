@@ -3,7 +3,10 @@ package scheduler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/koenno/currency-price-monitor/request"
 	"golang.org/x/exp/slog"
@@ -14,23 +17,82 @@ type Processor interface {
 	Process(context.Context, request.Descriptor) error
 }
 
+// Observer is told how long each processor took to handle a descriptor.
+type Observer interface {
+	ObserveProcessing(processor string, duration time.Duration, err error)
+}
+
+type options struct {
+	observer Observer
+}
+
+type Option func(*options)
+
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+type registration struct {
+	name string
+}
+
+type RegisterOption func(*registration)
+
+// WithName names the processor in observations, e.g. to tell apart two
+// processors of the same type. It defaults to the type name.
+func WithName(name string) RegisterOption {
+	return func(r *registration) {
+		r.name = name
+	}
+}
+
+type registered struct {
+	Processor
+	name string
+}
+
 type Scheduler struct {
-	processors []Processor
+	processors []registered
+	options    options
 }
 
-func NewScheduler() *Scheduler {
-	return &Scheduler{}
+func NewScheduler(opts ...Option) *Scheduler {
+	cfg := options{}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &Scheduler{
+		options: cfg,
+	}
 }
 
-func (r *Scheduler) Register(processor Processor) {
-	r.processors = append(r.processors, processor)
+func (r *Scheduler) Register(processor Processor, opts ...RegisterOption) {
+	cfg := registration{
+		name: strings.TrimPrefix(fmt.Sprintf("%T", processor), "*"),
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	r.processors = append(r.processors, registered{Processor: processor, name: cfg.name})
 }
 
+// Process hands descriptors to the registered processors until input is
+// closed or ctx is cancelled.
 func (s *Scheduler) Process(ctx context.Context, input <-chan request.Descriptor) {
-	for desc := range input {
-		err := s.processSingle(ctx, desc)
-		if err != nil {
-			slog.Error("failure while processing descriptor", "id", desc.ID, "error", err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case desc, ok := <-input:
+			if !ok {
+				return
+			}
+			err := s.processSingle(ctx, desc)
+			if err != nil {
+				slog.Error("failure while processing descriptor", "id", desc.ID, "error", err)
+			}
 		}
 	}
 }
@@ -43,10 +105,14 @@ func (s *Scheduler) processSingle(ctx context.Context, desc request.Descriptor)
 	)
 	wg.Add(len(s.processors))
 	for _, p := range s.processors {
-		go func(p Processor) {
+		go func(p registered) {
 			defer wg.Done()
 			defer errsMtx.Unlock()
+			start := time.Now()
 			err := p.Process(ctx, desc)
+			if s.options.observer != nil {
+				s.options.observer.ObserveProcessing(p.name, time.Since(start), err)
+			}
 			errsMtx.Lock()
 			errs = append(errs, err)
 		}(p)
@@ -3,11 +3,13 @@ package scheduler
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/koenno/currency-price-monitor/request"
 	"github.com/koenno/currency-price-monitor/scheduler/mocks"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -38,6 +40,30 @@ func TestShouldCallAllRegisteredProcessors(t *testing.T) {
 	procMock2.AssertExpectations(t)
 }
 
+func TestShouldStopProcessingWhenContextIsCancelled(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	input := make(chan request.Descriptor)
+	sut := NewScheduler()
+	sut.Register(procMock)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// when
+	go func() {
+		defer close(done)
+		sut.Process(ctx, input)
+	}()
+	cancel()
+
+	// then
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after cancellation")
+	}
+}
+
 func TestShouldContinueProcessingWhenSomeProcessorsFail(t *testing.T) {
 	// given
 	procMock1 := mocks.NewProcessor(t)
@@ -65,6 +91,74 @@ func TestShouldContinueProcessingWhenSomeProcessorsFail(t *testing.T) {
 	procMock2.AssertExpectations(t)
 }
 
+type observation struct {
+	processor string
+	err       error
+}
+
+type recordingObserver struct {
+	mtx          sync.Mutex
+	observations []observation
+}
+
+func (o *recordingObserver) ObserveProcessing(processor string, duration time.Duration, err error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.observations = append(o.observations, observation{processor: processor, err: err})
+}
+
+func TestShouldReportProcessingOfEachProcessorToObserver(t *testing.T) {
+	// given
+	procMock := mocks.NewProcessor(t)
+	observer := &recordingObserver{}
+	failure := errors.New("failure")
+	input := make(chan request.Descriptor, 2)
+	input <- newDescriptor("1")
+	input <- newDescriptor("2")
+	close(input)
+
+	sut := NewScheduler(WithObserver(observer))
+	sut.Register(procMock)
+
+	procMock.EXPECT().Process(mock.Anything, newDescriptor("1")).Return(nil).Once()
+	procMock.EXPECT().Process(mock.Anything, newDescriptor("2")).Return(failure).Once()
+
+	// when
+	sut.Process(context.Background(), input)
+
+	// then
+	assert.Equal(t, []observation{
+		{processor: "mocks.Processor"},
+		{processor: "mocks.Processor", err: failure},
+	}, observer.observations)
+}
+
+func TestShouldReportProcessorsUnderRegisteredNames(t *testing.T) {
+	// given
+	stdoutMock := mocks.NewProcessor(t)
+	logMock := mocks.NewProcessor(t)
+	observer := &recordingObserver{}
+	input := make(chan request.Descriptor, 1)
+	input <- newDescriptor("1")
+	close(input)
+
+	sut := NewScheduler(WithObserver(observer))
+	sut.Register(stdoutMock, WithName("stdout"))
+	sut.Register(logMock, WithName("log"))
+
+	stdoutMock.EXPECT().Process(mock.Anything, newDescriptor("1")).Return(nil).Once()
+	logMock.EXPECT().Process(mock.Anything, newDescriptor("1")).Return(nil).Once()
+
+	// when
+	sut.Process(context.Background(), input)
+
+	// then
+	assert.ElementsMatch(t, []observation{
+		{processor: "stdout"},
+		{processor: "log"},
+	}, observer.observations)
+}
+
 func newDescriptor(ID string) request.Descriptor {
 	return request.Descriptor{
 		ID:              ID,
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type FileStore struct {
+	path string
+
+	mtx     sync.Mutex
+	entries map[string]json.RawMessage
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		entries: make(map[string]json.RawMessage),
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state: %v", err)
+	}
+	if len(content) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(content, &s.entries); err != nil {
+		return nil, fmt.Errorf("unable to decode state %s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileStore) Load(key string, v any) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return load(s.entries, key, v)
+}
+
+func (s *FileStore) Save(key string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode state %s: %v", key, err)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	previous, existed := s.entries[key]
+	s.entries[key] = content
+	if err := s.flush(); err != nil {
+		if existed {
+			s.entries[key] = previous
+		} else {
+			delete(s.entries, key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	previous, existed := s.entries[key]
+	if !existed {
+		return nil
+	}
+	delete(s.entries, key)
+	if err := s.flush(); err != nil {
+		s.entries[key] = previous
+		return err
+	}
+	return nil
+}
+
+// flush must be called with mtx held.
+func (s *FileStore) flush() error {
+	content, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode state: %v", err)
+	}
+	if err := WriteFile(s.path, content, 0644); err != nil {
+		return fmt.Errorf("unable to save state: %v", err)
+	}
+	return nil
+}
+
+func load(entries map[string]json.RawMessage, key string, v any) error {
+	content, ok := entries[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("unable to decode state %s: %v", key, err)
+	}
+	return nil
+}
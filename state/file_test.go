@@ -0,0 +1,133 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	State    string    `json:"state"`
+	Notified time.Time `json:"notified"`
+}
+
+func TestShouldPersistStateAcrossInstances(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "state.json")
+	first, err := NewFileStore(path)
+	assert.NoError(t, err)
+	expected := entry{State: "above", Notified: time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC)}
+
+	// when
+	errSave := first.Save("threshold/EUR", expected)
+	second, errOpen := NewFileStore(path)
+	var actual entry
+	errLoad := second.Load("threshold/EUR", &actual)
+
+	// then
+	assert.NoError(t, errSave)
+	assert.NoError(t, errOpen)
+	assert.NoError(t, errLoad)
+	assert.Equal(t, expected, actual)
+}
+
+func TestShouldReportMissingState(t *testing.T) {
+	// given
+	sut, err := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	assert.NoError(t, err)
+
+	// when
+	var actual entry
+	err = sut.Load("threshold/EUR", &actual)
+
+	// then
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestShouldDeletePersistedState(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "state.json")
+	sut, err := NewFileStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Save("threshold/EUR", entry{State: "above"}))
+
+	// when
+	err = sut.Delete("threshold/EUR")
+	reopened, errOpen := NewFileStore(path)
+	var actual entry
+	errLoad := reopened.Load("threshold/EUR", &actual)
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, errOpen)
+	assert.ErrorIs(t, errLoad, ErrNotFound)
+}
+
+func TestShouldNotLeaveTemporaryFiles(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	sut, err := NewFileStore(filepath.Join(dir, "state.json"))
+	assert.NoError(t, err)
+
+	// when
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sut.Save("threshold/EUR", entry{State: "inside"}))
+	}
+
+	// then
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "state.json", files[0].Name())
+}
+
+func TestShouldKeepPreviousStateWhenWriteFails(t *testing.T) {
+	// given
+	dir := filepath.Join(t.TempDir(), "missing")
+	sut, err := NewFileStore(filepath.Join(dir, "state.json"))
+	assert.NoError(t, err)
+
+	// when
+	err = sut.Save("threshold/EUR", entry{State: "above"})
+	var actual entry
+	errLoad := sut.Load("threshold/EUR", &actual)
+
+	// then
+	assert.Error(t, err)
+	assert.ErrorIs(t, errLoad, ErrNotFound)
+}
+
+func TestShouldRejectCorruptedStateFile(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "state.json")
+	assert.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+
+	// when
+	_, err := NewFileStore(path)
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestShouldKeepStateInMemory(t *testing.T) {
+	// given
+	sut := NewMemoryStore()
+	expected := entry{State: "below"}
+
+	// when
+	errSave := sut.Save("threshold/EUR", expected)
+	var actual entry
+	errLoad := sut.Load("threshold/EUR", &actual)
+	errDelete := sut.Delete("threshold/EUR")
+	errMissing := sut.Load("threshold/EUR", &actual)
+
+	// then
+	assert.NoError(t, errSave)
+	assert.NoError(t, errLoad)
+	assert.Equal(t, expected, actual)
+	assert.NoError(t, errDelete)
+	assert.ErrorIs(t, errMissing, ErrNotFound)
+}
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+type MemoryStore struct {
+	mtx     sync.Mutex
+	entries map[string]json.RawMessage
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]json.RawMessage),
+	}
+}
+
+func (s *MemoryStore) Load(key string, v any) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return load(s.entries, key, v)
+}
+
+func (s *MemoryStore) Save(key string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode state %s: %v", key, err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.entries[key] = content
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.entries, key)
+	return nil
+}
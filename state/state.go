@@ -0,0 +1,48 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrNotFound = errors.New("state not found")
+)
+
+// Store keeps small JSON encodable values of processors across restarts.
+//
+//go:generate mockery --name=Store --case underscore --with-expecter
+type Store interface {
+	Load(key string, v any) error
+	Save(key string, v any) error
+	Delete(key string) error
+}
+
+// WriteFile replaces the file so that readers never observe a partial write.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to replace %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/state"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".ndjson"
+
+	defaultSegmentSize = 4 << 20
+	defaultMaxSegments = 8
+)
+
+type fileOptions struct {
+	segmentSize int64
+	maxSegments int
+}
+
+type FileOption func(*fileOptions)
+
+func WithSegmentSize(bytes int64) FileOption {
+	return func(o *fileOptions) {
+		o.segmentSize = bytes
+	}
+}
+
+func WithMaxSegments(n int) FileOption {
+	return func(o *fileOptions) {
+		o.maxSegments = n
+	}
+}
+
+// FileStore keeps rates in append-only newline delimited JSON segments.
+// Later records of the same currency, source and date replace earlier ones
+// and compaction rewrites the live records into a single segment.
+type FileStore struct {
+	dir     string
+	options fileOptions
+
+	mtx      sync.Mutex
+	records  map[recordKey]Record
+	segments []int
+	active   *os.File
+	size     int64
+}
+
+func OpenFileStore(dir string, opts ...FileOption) (*FileStore, error) {
+	cfg := fileOptions{
+		segmentSize: defaultSegmentSize,
+		maxSegments: defaultMaxSegments,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create store directory: %v", err)
+	}
+	s := &FileStore{
+		dir:     dir,
+		options: cfg,
+		records: make(map[recordKey]Record),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Upsert(ctx context.Context, records []Record) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		return ErrClosed
+	}
+
+	var buf bytes.Buffer
+	changed := make([]Record, 0, len(records))
+	for _, r := range records {
+		r.Date = day(r.Date)
+		if existing, ok := s.records[keyOf(r)]; ok && existing.Value == r.Value {
+			continue
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("unable to encode rate: %v", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		changed = append(changed, r)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := s.append(buf.Bytes()); err != nil {
+		return err
+	}
+	for _, r := range changed {
+		s.records[keyOf(r)] = r
+	}
+	if s.size >= s.options.segmentSize {
+		return s.roll()
+	}
+	return nil
+}
+
+func (s *FileStore) Range(ctx context.Context, query Query) ([]Record, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		return nil, ErrClosed
+	}
+
+	var result []Record
+	for _, r := range s.records {
+		if query.matches(r) {
+			result = append(result, r)
+		}
+	}
+	sortRecords(result)
+	return result, nil
+}
+
+func (s *FileStore) Latest(ctx context.Context, currency, source string) (Record, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		return Record{}, ErrClosed
+	}
+
+	var (
+		latest Record
+		found  bool
+	)
+	query := Query{Currency: currency, Source: source}
+	for _, r := range s.records {
+		if !query.matches(r) {
+			continue
+		}
+		if !found || r.Date.After(latest.Date) || (r.Date.Equal(latest.Date) && r.Source < latest.Source) {
+			latest = r
+			found = true
+		}
+	}
+	if !found {
+		return Record{}, fmt.Errorf("%w: %s", ErrNotFound, currency)
+	}
+	return latest, nil
+}
+
+func (s *FileStore) LastDate(ctx context.Context, currency string) (time.Time, bool, error) {
+	latest, err := s.Latest(ctx, currency, "")
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return latest.Date, true, nil
+}
+
+func (s *FileStore) Compact() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		return ErrClosed
+	}
+	return s.compact()
+}
+
+func (s *FileStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.records == nil {
+		return nil
+	}
+	s.records = nil
+	if s.active == nil {
+		return nil
+	}
+	return s.active.Close()
+}
+
+func (s *FileStore) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("unable to list segments: %v", err)
+	}
+	for _, e := range entries {
+		seq, ok := parseSegmentName(e.Name())
+		if !ok {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+	}
+	sort.Ints(s.segments)
+
+	for i, seq := range s.segments {
+		last := i == len(s.segments)-1
+		if err := s.replay(seq, last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay reads a segment into memory. A torn trailing line of the last
+// segment, left by a crash during append, is truncated.
+func (s *FileStore) replay(seq int, last bool) error {
+	path := s.segmentPath(seq)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open segment %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var (
+		reader = bufio.NewReader(f)
+		offset int64
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("unable to read segment %s: %v", path, err)
+		}
+		var r Record
+		if decodeErr := json.Unmarshal(line, &r); decodeErr != nil || err == io.EOF {
+			if last && isTail(reader) {
+				return os.Truncate(path, offset)
+			}
+			return fmt.Errorf("corrupted segment %s at offset %d", path, offset)
+		}
+		s.records[keyOf(r)] = r
+		offset += int64(len(line))
+	}
+}
+
+func isTail(reader *bufio.Reader) bool {
+	_, err := reader.Peek(1)
+	return err == io.EOF
+}
+
+// append must be called with mtx held.
+func (s *FileStore) append(data []byte) error {
+	if s.active == nil {
+		if err := s.openActive(); err != nil {
+			return err
+		}
+	}
+	n, err := s.active.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("unable to append to segment: %v", err)
+	}
+	if err := s.active.Sync(); err != nil {
+		return fmt.Errorf("unable to sync segment: %v", err)
+	}
+	return nil
+}
+
+// openActive must be called with mtx held.
+func (s *FileStore) openActive() error {
+	if len(s.segments) == 0 {
+		s.segments = append(s.segments, 1)
+	}
+	path := s.segmentPath(s.segments[len(s.segments)-1])
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open segment %s: %v", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to open segment %s: %v", path, err)
+	}
+	s.active = f
+	s.size = info.Size()
+	return nil
+}
+
+// roll must be called with mtx held.
+func (s *FileStore) roll() error {
+	if err := s.active.Close(); err != nil {
+		return fmt.Errorf("unable to seal segment: %v", err)
+	}
+	s.active = nil
+	s.size = 0
+	s.segments = append(s.segments, s.segments[len(s.segments)-1]+1)
+	if len(s.segments) > s.options.maxSegments {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact must be called with mtx held.
+func (s *FileStore) compact() error {
+	records := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sortRecords(records)
+
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("unable to encode rate: %v", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	next := 1
+	if len(s.segments) > 0 {
+		next = s.segments[len(s.segments)-1] + 1
+	}
+	if err := state.WriteFile(s.segmentPath(next), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write compacted segment: %v", err)
+	}
+
+	if s.active != nil {
+		s.active.Close()
+		s.active = nil
+	}
+	for _, seq := range s.segments {
+		if err := os.Remove(s.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove compacted segment: %v", err)
+		}
+	}
+	s.segments = []int{next, next + 1}
+	s.size = 0
+	return nil
+}
+
+func (s *FileStore) segmentPath(seq int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%06d%s", segmentPrefix, seq, segmentSuffix))
+}
+
+func parseSegmentName(name string) (int, bool) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+		return 0, false
+	}
+	seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Date.Equal(records[j].Date) {
+			return records[i].Date.Before(records[j].Date)
+		}
+		if records[i].Currency != records[j].Currency {
+			return records[i].Currency < records[j].Currency
+		}
+		return records[i].Source < records[j].Source
+	})
+}
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func newRecord(currency, source, day string, value float64) Record {
+	return Record{
+		Currency: currency,
+		Source:   source,
+		Date:     date(day),
+		Value:    value,
+	}
+}
+
+func TestShouldQueryUpsertedRates(t *testing.T) {
+	// given
+	sut, err := OpenFileStore(t.TempDir())
+	assert.NoError(t, err)
+	defer sut.Close()
+	ctx := context.Background()
+
+	// when
+	err = sut.Upsert(ctx, []Record{
+		newRecord("EUR", "nbp", "2023-10-04", 4.62),
+		newRecord("EUR", "nbp", "2023-10-02", 4.60),
+		newRecord("EUR", "nbp", "2023-10-03", 4.61),
+		newRecord("USD", "nbp", "2023-10-03", 4.35),
+	})
+	rates, errRange := sut.Range(ctx, Query{Currency: "EUR", From: date("2023-10-03"), To: date("2023-10-04")})
+	latest, errLatest := sut.Latest(ctx, "EUR", "")
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, errRange)
+	assert.Equal(t, []Record{
+		newRecord("EUR", "nbp", "2023-10-03", 4.61),
+		newRecord("EUR", "nbp", "2023-10-04", 4.62),
+	}, rates)
+	assert.NoError(t, errLatest)
+	assert.Equal(t, newRecord("EUR", "nbp", "2023-10-04", 4.62), latest)
+}
+
+func TestShouldReplaceRateOfTheSameSourceAndDate(t *testing.T) {
+	// given
+	sut, err := OpenFileStore(t.TempDir())
+	assert.NoError(t, err)
+	defer sut.Close()
+	ctx := context.Background()
+
+	// when
+	assert.NoError(t, sut.Upsert(ctx, []Record{
+		newRecord("EUR", "nbp", "2023-10-03", 4.61),
+		newRecord("EUR", "ecb", "2023-10-03", 4.63),
+	}))
+	assert.NoError(t, sut.Upsert(ctx, []Record{newRecord("EUR", "nbp", "2023-10-03", 4.65)}))
+	nbp, errNBP := sut.Range(ctx, Query{Currency: "EUR", Source: "nbp"})
+	all, errAll := sut.Range(ctx, Query{Currency: "EUR"})
+
+	// then
+	assert.NoError(t, errNBP)
+	assert.Equal(t, []Record{newRecord("EUR", "nbp", "2023-10-03", 4.65)}, nbp)
+	assert.NoError(t, errAll)
+	assert.Len(t, all, 2)
+}
+
+func TestShouldReportMissingLatestRate(t *testing.T) {
+	// given
+	sut, err := OpenFileStore(t.TempDir())
+	assert.NoError(t, err)
+	defer sut.Close()
+
+	// when
+	_, err = sut.Latest(context.Background(), "EUR", "")
+	_, found, errLastDate := sut.LastDate(context.Background(), "EUR")
+
+	// then
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, errLastDate)
+	assert.False(t, found)
+}
+
+func TestShouldRestoreRatesAfterReopen(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	first, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Upsert(context.Background(), []Record{
+		newRecord("EUR", "nbp", "2023-10-02", 4.60),
+		newRecord("EUR", "nbp", "2023-10-03", 4.61),
+	}))
+	assert.NoError(t, first.Upsert(context.Background(), []Record{newRecord("EUR", "nbp", "2023-10-03", 4.65)}))
+	assert.NoError(t, first.Close())
+
+	// when
+	second, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	defer second.Close()
+	rates, errRange := second.Range(context.Background(), Query{Currency: "EUR"})
+	lastDate, found, errLastDate := second.LastDate(context.Background(), "EUR")
+
+	// then
+	assert.NoError(t, errRange)
+	assert.Equal(t, []Record{
+		newRecord("EUR", "nbp", "2023-10-02", 4.60),
+		newRecord("EUR", "nbp", "2023-10-03", 4.65),
+	}, rates)
+	assert.NoError(t, errLastDate)
+	assert.True(t, found)
+	assert.Equal(t, date("2023-10-03"), lastDate)
+}
+
+func TestShouldNotAppendUnchangedRates(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	sut, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	defer sut.Close()
+	records := []Record{newRecord("EUR", "nbp", "2023-10-03", 4.61)}
+	assert.NoError(t, sut.Upsert(context.Background(), records))
+	before := segmentSizes(t, dir)
+
+	// when
+	err = sut.Upsert(context.Background(), records)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, before, segmentSizes(t, dir))
+}
+
+func TestShouldRollAndCompactSegments(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	sut, err := OpenFileStore(dir, WithSegmentSize(1), WithMaxSegments(3))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	// when
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sut.Upsert(ctx, []Record{newRecord("EUR", "nbp", "2023-10-03", 4.60+float64(i)/100)}))
+		assert.NoError(t, sut.Upsert(ctx, []Record{newRecord("USD", "nbp", "2023-10-03", 4.30+float64(i)/100)}))
+	}
+	assert.NoError(t, sut.Close())
+	reopened, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	eur, errEUR := reopened.Latest(ctx, "EUR", "nbp")
+	usd, errUSD := reopened.Latest(ctx, "USD", "nbp")
+
+	// then
+	assert.LessOrEqual(t, len(segmentSizes(t, dir)), 3)
+	assert.NoError(t, errEUR)
+	assert.InDelta(t, 4.64, eur.Value, 1e-9)
+	assert.NoError(t, errUSD)
+	assert.InDelta(t, 4.34, usd.Value, 1e-9)
+}
+
+func TestShouldCompactIntoSingleSegment(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	sut, err := OpenFileStore(dir, WithSegmentSize(1))
+	assert.NoError(t, err)
+	defer sut.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sut.Upsert(ctx, []Record{newRecord("EUR", "nbp", "2023-10-03", 4.60+float64(i)/100)}))
+	}
+
+	// when
+	err = sut.Compact()
+	errUpsert := sut.Upsert(ctx, []Record{newRecord("EUR", "nbp", "2023-10-04", 4.70)})
+	rates, errRange := sut.Range(ctx, Query{Currency: "EUR"})
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, errUpsert)
+	assert.NoError(t, errRange)
+	assert.Len(t, rates, 2)
+	assert.Len(t, segmentSizes(t, dir), 2)
+}
+
+func TestShouldTruncateTornTrailingRecord(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	first, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Upsert(context.Background(), []Record{newRecord("EUR", "nbp", "2023-10-03", 4.61)}))
+	assert.NoError(t, first.Close())
+	f, err := os.OpenFile(filepath.Join(dir, "segment-000001.ndjson"), os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"currency":"EUR","sour`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	// when
+	second, err := OpenFileStore(dir)
+	assert.NoError(t, err)
+	defer second.Close()
+	errUpsert := second.Upsert(context.Background(), []Record{newRecord("EUR", "nbp", "2023-10-04", 4.62)})
+	third, errReopen := OpenFileStore(dir)
+
+	// then
+	assert.NoError(t, errUpsert)
+	assert.NoError(t, errReopen)
+	defer third.Close()
+	rates, err := third.Range(context.Background(), Query{Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+}
+
+func TestShouldRejectOperationsAfterClose(t *testing.T) {
+	// given
+	sut, err := OpenFileStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Close())
+
+	// when
+	err = sut.Upsert(context.Background(), []Record{newRecord("EUR", "nbp", "2023-10-03", 4.61)})
+
+	// then
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func segmentSizes(t *testing.T, dir string) map[string]int64 {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	sizes := make(map[string]int64)
+	for _, e := range entries {
+		if _, ok := parseSegmentName(e.Name()); !ok {
+			continue
+		}
+		info, err := e.Info()
+		assert.NoError(t, err)
+		sizes[e.Name()] = info.Size()
+	}
+	return sizes
+}
@@ -0,0 +1,238 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	dateLayout = time.DateOnly
+	timeLayout = time.RFC3339Nano
+)
+
+var migrations = []string{
+	`CREATE TABLE rates (
+		currency   TEXT NOT NULL,
+		source     TEXT NOT NULL,
+		date       TEXT NOT NULL,
+		value      REAL NOT NULL,
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (currency, source, date)
+	)`,
+	`CREATE TABLE descriptors (
+		id                TEXT PRIMARY KEY,
+		target            TEXT NOT NULL,
+		url               TEXT NOT NULL,
+		time              TEXT NOT NULL,
+		valid_status_code INTEGER NOT NULL,
+		json              INTEGER NOT NULL,
+		valid             INTEGER NOT NULL,
+		duration_ms       REAL NOT NULL,
+		currency          TEXT NOT NULL,
+		rates             INTEGER NOT NULL,
+		backfill          INTEGER NOT NULL
+	)`,
+	`CREATE INDEX rates_by_date ON rates (currency, date)`,
+	`CREATE INDEX descriptors_by_time ON descriptors (time)`,
+	`ALTER TABLE descriptors ADD COLUMN status_code INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE descriptors ADD COLUMN response_size INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE descriptors ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE descriptors ADD COLUMN error_kind TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE descriptors ADD COLUMN error TEXT NOT NULL DEFAULT ''`,
+}
+
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database %s: %v", path, err)
+	}
+	db.SetMaxOpenConns(1)
+	s := &Store{
+		db:  db,
+		now: time.Now,
+	}
+	if err := s.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to migrate database: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("unable to migrate database: %v", err)
+	}
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("unable to read schema version: %v", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			return fmt.Errorf("unable to apply migration %d: %v", i+1, err)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			i+1, s.now().UTC().Format(timeLayout))
+		if err != nil {
+			return fmt.Errorf("unable to record migration %d: %v", i+1, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Upsert(ctx context.Context, records []store.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to store rates: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO rates (currency, source, date, value, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (currency, source, date) DO UPDATE
+		SET value = excluded.value, updated_at = excluded.updated_at
+		WHERE value != excluded.value`)
+	if err != nil {
+		return fmt.Errorf("unable to store rates: %v", err)
+	}
+	defer stmt.Close()
+
+	updatedAt := s.now().UTC().Format(timeLayout)
+	for _, r := range records {
+		_, err := stmt.ExecContext(ctx, r.Currency, r.Source, r.Date.UTC().Format(dateLayout), r.Value, updatedAt)
+		if err != nil {
+			return fmt.Errorf("unable to store %s rate of %s: %v", r.Currency, r.Date.Format(dateLayout), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to store rates: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) Range(ctx context.Context, query store.Query) ([]store.Record, error) {
+	stmt := `SELECT currency, source, date, value FROM rates WHERE currency = ?`
+	args := []any{query.Currency}
+	if query.Source != "" {
+		stmt += ` AND source = ?`
+		args = append(args, query.Source)
+	}
+	if !query.From.IsZero() {
+		stmt += ` AND date >= ?`
+		args = append(args, query.From.UTC().Format(dateLayout))
+	}
+	if !query.To.IsZero() {
+		stmt += ` AND date <= ?`
+		args = append(args, query.To.UTC().Format(dateLayout))
+	}
+	stmt += ` ORDER BY date, source`
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query rates: %v", err)
+	}
+	defer rows.Close()
+
+	var result []store.Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query rates: %v", err)
+	}
+	return result, nil
+}
+
+func (s *Store) Latest(ctx context.Context, currency, source string) (store.Record, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT currency, source, date, value FROM rates
+		WHERE currency = ? AND (? = '' OR source = ?)
+		ORDER BY date DESC, source
+		LIMIT 1`, currency, source, source)
+	r, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Record{}, fmt.Errorf("%w: %s", store.ErrNotFound, currency)
+	}
+	return r, err
+}
+
+func (s *Store) LastDate(ctx context.Context, currency string) (time.Time, bool, error) {
+	latest, err := s.Latest(ctx, currency, "")
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return latest.Date, true, nil
+}
+
+func (s *Store) AppendDescriptor(ctx context.Context, desc request.Descriptor) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO descriptors
+		(id, target, url, time, valid_status_code, json, valid, duration_ms, currency, rates, backfill,
+		 status_code, response_size, attempts, error_kind, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		desc.ID, desc.Target, desc.URL, desc.Time.UTC().Format(timeLayout),
+		desc.ValidStatusCode, desc.JSON, desc.Valid, float64(desc.Duration)/float64(time.Millisecond),
+		desc.Payload.Name, len(desc.Payload.Rates), desc.Backfill,
+		desc.StatusCode, desc.ResponseSize, desc.Attempts, string(desc.ErrorKind), desc.Error)
+	if err != nil {
+		return fmt.Errorf("unable to store descriptor %s: %v", desc.ID, err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row scanner) (store.Record, error) {
+	var (
+		r    store.Record
+		date string
+	)
+	if err := row.Scan(&r.Currency, &r.Source, &date, &r.Value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.Record{}, err
+		}
+		return store.Record{}, fmt.Errorf("unable to read rate: %v", err)
+	}
+	parsed, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return store.Record{}, fmt.Errorf("unable to parse rate date %s: %v", date, err)
+	}
+	r.Date = parsed
+	return r, nil
+}
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/koenno/currency-price-monitor/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func newRecord(currency, source, day string, value float64) store.Record {
+	return store.Record{
+		Currency: currency,
+		Source:   source,
+		Date:     date(day),
+		Value:    value,
+	}
+}
+
+func openStore(t *testing.T, path string) *Store {
+	s, err := Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+func TestShouldApplyMigrationsOnce(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "rates.db")
+	first, err := Open(path)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+
+	// when
+	sut := openStore(t, path)
+	var applied int
+	err = sut.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestShouldUpsertAndQueryRates(t *testing.T) {
+	// given
+	sut := openStore(t, filepath.Join(t.TempDir(), "rates.db"))
+	ctx := context.Background()
+
+	// when
+	errFirst := sut.Upsert(ctx, []store.Record{
+		newRecord("EUR", "nbp", "2023-10-02", 4.60),
+		newRecord("EUR", "nbp", "2023-10-03", 4.61),
+		newRecord("EUR", "ecb", "2023-10-03", 4.63),
+		newRecord("USD", "nbp", "2023-10-03", 4.35),
+	})
+	errSecond := sut.Upsert(ctx, []store.Record{newRecord("EUR", "nbp", "2023-10-03", 4.65)})
+	rates, errRange := sut.Range(ctx, store.Query{Currency: "EUR", Source: "nbp", From: date("2023-10-03")})
+	latest, errLatest := sut.Latest(ctx, "EUR", "")
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.NoError(t, errRange)
+	assert.Equal(t, []store.Record{newRecord("EUR", "nbp", "2023-10-03", 4.65)}, rates)
+	assert.NoError(t, errLatest)
+	assert.Equal(t, newRecord("EUR", "ecb", "2023-10-03", 4.63), latest)
+}
+
+func TestShouldKeepUnchangedRatesUntouched(t *testing.T) {
+	// given
+	sut := openStore(t, filepath.Join(t.TempDir(), "rates.db"))
+	ctx := context.Background()
+	sut.now = func() time.Time { return time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC) }
+	records := []store.Record{newRecord("EUR", "nbp", "2023-10-03", 4.61)}
+	assert.NoError(t, sut.Upsert(ctx, records))
+
+	// when
+	sut.now = func() time.Time { return time.Date(2023, 10, 4, 12, 0, 0, 0, time.UTC) }
+	err := sut.Upsert(ctx, records)
+	var updatedAt string
+	errQuery := sut.db.QueryRow(`SELECT updated_at FROM rates WHERE currency = 'EUR'`).Scan(&updatedAt)
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, errQuery)
+	assert.Equal(t, "2023-10-03T12:00:00Z", updatedAt)
+}
+
+func TestShouldReportMissingRate(t *testing.T) {
+	// given
+	sut := openStore(t, filepath.Join(t.TempDir(), "rates.db"))
+
+	// when
+	_, err := sut.Latest(context.Background(), "EUR", "")
+	_, found, errLastDate := sut.LastDate(context.Background(), "EUR")
+
+	// then
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.NoError(t, errLastDate)
+	assert.False(t, found)
+}
+
+func TestShouldAppendDescriptorMetadata(t *testing.T) {
+	// given
+	sut := openStore(t, filepath.Join(t.TempDir(), "rates.db"))
+	desc := request.Descriptor{
+		ID:              "1",
+		Target:          "nbp-eur",
+		URL:             "http://api.nbp.pl/api/exchangerates/rates/a/eur",
+		Time:            time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC),
+		ValidStatusCode: true,
+		JSON:            true,
+		Valid:           true,
+		Duration:        250 * time.Millisecond,
+		Payload: request.Currency{
+			Name:  "EUR",
+			Rates: []request.Rate{{Date: date("2023-10-03"), Value: 4.61}},
+		},
+	}
+
+	// when
+	errFirst := sut.AppendDescriptor(context.Background(), desc)
+	errDuplicate := sut.AppendDescriptor(context.Background(), desc)
+	var (
+		count      int
+		url        string
+		durationMs float64
+		valid      bool
+		rates      int
+	)
+	errQuery := sut.db.QueryRow(`SELECT COUNT(*), url, duration_ms, valid, rates FROM descriptors`).
+		Scan(&count, &url, &durationMs, &valid, &rates)
+
+	// then
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errDuplicate)
+	assert.NoError(t, errQuery)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, desc.URL, url)
+	assert.Equal(t, 250.0, durationMs)
+	assert.True(t, valid)
+	assert.Equal(t, 1, rates)
+}
+
+func TestShouldAppendDescriptorErrorClassification(t *testing.T) {
+	// given
+	sut := openStore(t, filepath.Join(t.TempDir(), "rates.db"))
+	desc := request.Descriptor{
+		ID:           "1",
+		Time:         time.Date(2023, 10, 3, 12, 0, 0, 0, time.UTC),
+		StatusCode:   503,
+		ResponseSize: 42,
+		Attempts:     3,
+		ErrorKind:    request.ErrorHTTPStatus,
+		Error:        "status code 503",
+	}
+
+	// when
+	err := sut.AppendDescriptor(context.Background(), desc)
+	var (
+		statusCode   int
+		responseSize int64
+		attempts     int
+		errorKind    string
+		errorText    string
+	)
+	errQuery := sut.db.QueryRow(`SELECT status_code, response_size, attempts, error_kind, error FROM descriptors`).
+		Scan(&statusCode, &responseSize, &attempts, &errorKind, &errorText)
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, errQuery)
+	assert.Equal(t, 503, statusCode)
+	assert.Equal(t, int64(42), responseSize)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, "http_status", errorKind)
+	assert.Equal(t, "status code 503", errorText)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+var (
+	ErrNotFound = errors.New("rate not found")
+	ErrClosed   = errors.New("store closed")
+)
+
+type Record struct {
+	Currency string    `json:"currency"`
+	Source   string    `json:"source"`
+	Date     time.Time `json:"date"`
+	Value    float64   `json:"value"`
+}
+
+// Query selects records of a currency published within [From, To].
+// Empty Source matches every source and zero bounds are open.
+type Query struct {
+	Currency string
+	Source   string
+	From     time.Time
+	To       time.Time
+}
+
+func (q Query) matches(r Record) bool {
+	if r.Currency != q.Currency {
+		return false
+	}
+	if q.Source != "" && r.Source != q.Source {
+		return false
+	}
+	if !q.From.IsZero() && r.Date.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && r.Date.After(q.To) {
+		return false
+	}
+	return true
+}
+
+//go:generate mockery --name=RateStore --case underscore --with-expecter
+type RateStore interface {
+	Upsert(ctx context.Context, records []Record) error
+	Range(ctx context.Context, query Query) ([]Record, error)
+	Latest(ctx context.Context, currency, source string) (Record, error)
+}
+
+//go:generate mockery --name=DescriptorLog --case underscore --with-expecter
+type DescriptorLog interface {
+	AppendDescriptor(ctx context.Context, desc request.Descriptor) error
+}
+
+type recordKey struct {
+	currency string
+	source   string
+	date     time.Time
+}
+
+func keyOf(r Record) recordKey {
+	return recordKey{
+		currency: r.Currency,
+		source:   r.Source,
+		date:     day(r.Date),
+	}
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
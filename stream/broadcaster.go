@@ -0,0 +1,335 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/request"
+)
+
+const (
+	EventRate  = "rate"
+	EventAlert = "alert"
+
+	defaultReplaySize   = 1000
+	defaultClientBuffer = 64
+	defaultHeartbeat    = 15 * time.Second
+
+	// idsPerMilli leaves room for that many events per millisecond of uptime
+	// before ids of a restarted broadcaster could repeat, while staying below
+	// 2^53 so JavaScript clients read them exactly.
+	idsPerMilli = 1000
+)
+
+type Event struct {
+	ID       uint64
+	Type     string
+	Currency string
+	Data     json.RawMessage
+}
+
+type rateData struct {
+	Currency string  `json:"currency"`
+	Source   string  `json:"source"`
+	Date     string  `json:"date"`
+	Value    float64 `json:"value"`
+}
+
+type alertData struct {
+	Key      string         `json:"key"`
+	Name     string         `json:"name"`
+	Currency string         `json:"currency"`
+	Date     string         `json:"date"`
+	Rate     float64        `json:"rate"`
+	Severity alert.Severity `json:"severity"`
+	Message  string         `json:"message"`
+	Time     time.Time      `json:"time"`
+	Resolved bool           `json:"resolved"`
+}
+
+type options struct {
+	replaySize   int
+	clientBuffer int
+	heartbeat    time.Duration
+}
+
+type Option func(*options)
+
+// WithReplaySize sets how many recent events are kept for clients
+// reconnecting with Last-Event-ID.
+func WithReplaySize(n int) Option {
+	return func(o *options) {
+		o.replaySize = n
+	}
+}
+
+// WithClientBuffer sets how many events may wait for a client before it is
+// disconnected as too slow.
+func WithClientBuffer(n int) Option {
+	return func(o *options) {
+		o.clientBuffer = n
+	}
+}
+
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = interval
+	}
+}
+
+type client struct {
+	currencies map[string]bool
+	events     chan Event
+	dropped    chan struct{}
+}
+
+func (c *client) wants(e Event) bool {
+	return len(c.currencies) == 0 || c.currencies[e.Currency]
+}
+
+// Broadcaster is a scheduler processor pushing new rates and alerts to
+// clients connected over Server-Sent Events or WebSocket. Broadcasting never
+// blocks: a client whose buffer is full is disconnected and may resume with
+// Last-Event-ID.
+type Broadcaster struct {
+	options options
+
+	mtx     sync.Mutex
+	nextID  uint64
+	replay  []Event
+	clients map[*client]struct{}
+	latest  map[string]time.Time
+}
+
+func NewBroadcaster(opts ...Option) *Broadcaster {
+	cfg := options{
+		replaySize:   defaultReplaySize,
+		clientBuffer: defaultClientBuffer,
+		heartbeat:    defaultHeartbeat,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &Broadcaster{
+		options: cfg,
+		nextID:  firstID(time.Now()),
+		clients: make(map[*client]struct{}),
+		latest:  make(map[string]time.Time),
+	}
+}
+
+// Process broadcasts rates newer than the ones already seen for the
+// currency; for an unseen currency only its most recent rate is sent.
+func (b *Broadcaster) Process(ctx context.Context, desc request.Descriptor) error {
+	if desc.Failed() || len(desc.Payload.Rates) == 0 {
+		return nil
+	}
+	currency := desc.Payload.Name
+	rates := sortedRates(desc.Payload.Rates)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	last, seen := b.latest[currency]
+	if !seen {
+		rates = rates[len(rates)-1:]
+	}
+	for _, r := range rates {
+		if seen && !r.Date.After(last) {
+			continue
+		}
+		data, err := json.Marshal(rateData{
+			Currency: currency,
+			Source:   desc.Target,
+			Date:     r.Date.Format(time.DateOnly),
+			Value:    r.Value,
+		})
+		if err != nil {
+			return err
+		}
+		b.publish(EventRate, currency, data)
+		b.latest[currency] = r.Date
+	}
+	return nil
+}
+
+// AlertSink broadcasts alerts before passing them to next.
+func (b *Broadcaster) AlertSink(next alert.Sink) alert.Sink {
+	return alertBroadcaster{
+		next:        next,
+		broadcaster: b,
+	}
+}
+
+type alertBroadcaster struct {
+	next        alert.Sink
+	broadcaster *Broadcaster
+}
+
+func (s alertBroadcaster) Notify(ctx context.Context, events []alert.Event) error {
+	b := s.broadcaster
+	b.mtx.Lock()
+	for _, e := range events {
+		data, err := json.Marshal(alertData{
+			Key:      e.Key,
+			Name:     e.Name,
+			Currency: e.Currency,
+			Date:     e.Rate.Date.Format(time.DateOnly),
+			Rate:     e.Rate.Value,
+			Severity: e.Severity,
+			Message:  e.Message,
+			Time:     e.Time,
+			Resolved: e.Resolved,
+		})
+		if err != nil {
+			b.mtx.Unlock()
+			return err
+		}
+		b.publish(EventAlert, e.Currency, data)
+	}
+	b.mtx.Unlock()
+	return s.next.Notify(ctx, events)
+}
+
+// ServeHTTP streams events over WebSocket when the request asks for an
+// upgrade and over Server-Sent Events otherwise. The currency query
+// parameter holds a comma separated list of currencies to receive.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if isWebSocketUpgrade(r) {
+		b.serveWebSocket(w, r)
+		return
+	}
+	b.serveSSE(w, r)
+}
+
+// publish must be called with mtx held.
+func (b *Broadcaster) publish(eventType, currency string, data json.RawMessage) {
+	e := Event{
+		ID:       b.nextID,
+		Type:     eventType,
+		Currency: currency,
+		Data:     data,
+	}
+	b.nextID++
+	if b.options.replaySize > 0 {
+		if len(b.replay) == b.options.replaySize {
+			b.replay = append(b.replay[:0], b.replay[1:]...)
+		}
+		b.replay = append(b.replay, e)
+	}
+	for c := range b.clients {
+		if !c.wants(e) {
+			continue
+		}
+		select {
+		case c.events <- e:
+		default:
+			b.drop(c)
+		}
+	}
+}
+
+// subscribe registers a client and returns the kept events after lastID,
+// so no event is lost between the replay and the live stream. An id this
+// broadcaster has not issued yet comes from elsewhere, so all kept events
+// are returned.
+func (b *Broadcaster) subscribe(currencies map[string]bool, lastID uint64, resume bool) (*client, []Event) {
+	c := &client{
+		currencies: currencies,
+		events:     make(chan Event, b.options.clientBuffer),
+		dropped:    make(chan struct{}),
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.clients[c] = struct{}{}
+	if !resume {
+		return c, nil
+	}
+	if lastID >= b.nextID {
+		lastID = 0
+	}
+	var missed []Event
+	for _, e := range b.replay {
+		if e.ID > lastID && c.wants(e) {
+			missed = append(missed, e)
+		}
+	}
+	return c, missed
+}
+
+func (b *Broadcaster) unsubscribe(c *client) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.drop(c)
+}
+
+// drop must be called with mtx held.
+func (b *Broadcaster) drop(c *client) {
+	if _, ok := b.clients[c]; !ok {
+		return
+	}
+	delete(b.clients, c)
+	close(c.dropped)
+}
+
+func (b *Broadcaster) clientCount() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.clients)
+}
+
+// firstID seeds event ids from the start time, so ids keep growing across
+// restarts and clients resuming with an id of an earlier run get all kept
+// events instead of none.
+func firstID(start time.Time) uint64 {
+	return uint64(start.UnixMilli())*idsPerMilli + 1
+}
+
+func parseCurrencies(r *http.Request) map[string]bool {
+	currencies := make(map[string]bool)
+	for _, value := range r.URL.Query()["currency"] {
+		for _, c := range strings.Split(value, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				currencies[strings.ToUpper(c)] = true
+			}
+		}
+	}
+	return currencies
+}
+
+// lastEventID reads the id to resume from, sent by EventSource in the
+// Last-Event-ID header or by other clients in the last_event_id parameter.
+func lastEventID(r *http.Request) (uint64, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+func sortedRates(rates []request.Rate) []request.Rate {
+	sorted := make([]request.Rate, len(rates))
+	copy(sorted, rates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return sorted
+}
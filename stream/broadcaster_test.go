@@ -0,0 +1,285 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koenno/currency-price-monitor/alert"
+	"github.com/koenno/currency-price-monitor/alert/mocks"
+	"github.com/koenno/currency-price-monitor/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func newRatesDescriptor(currency string, rates ...request.Rate) request.Descriptor {
+	return request.Descriptor{
+		ID:     "1",
+		Target: "nbp-" + strings.ToLower(currency),
+		Payload: request.Currency{
+			Name:  currency,
+			Rates: rates,
+		},
+	}
+}
+
+func newRate(d string, value float64) request.Rate {
+	return request.Rate{Date: date(d), Value: value}
+}
+
+func receive(t *testing.T, c *client) Event {
+	t.Helper()
+	select {
+	case e := <-c.events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestShouldBroadcastOnlyNewRates(t *testing.T) {
+	// given
+	sut := NewBroadcaster()
+	c, _ := sut.subscribe(nil, 0, false)
+	firstID := sut.nextID
+
+	// when
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61), newRate("2023-10-02", 4.6)))
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61), newRate("2023-10-04", 4.62)))
+	sut.Process(context.Background(), request.Descriptor{ErrorKind: request.ErrorTransport})
+
+	// then
+	first := receive(t, c)
+	second := receive(t, c)
+	assert.Equal(t, firstID, first.ID)
+	assert.Equal(t, EventRate, first.Type)
+	assert.JSONEq(t, `{"currency": "EUR", "source": "nbp-eur", "date": "2023-10-03", "value": 4.61}`, string(first.Data))
+	assert.Equal(t, firstID+1, second.ID)
+	assert.JSONEq(t, `{"currency": "EUR", "source": "nbp-eur", "date": "2023-10-04", "value": 4.62}`, string(second.Data))
+	assert.Empty(t, c.events)
+}
+
+func TestShouldSendOnlyFilteredCurrencies(t *testing.T) {
+	// given
+	sut := NewBroadcaster()
+	c, _ := sut.subscribe(map[string]bool{"USD": true}, 0, false)
+
+	// when
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61)))
+	sut.Process(context.Background(), newRatesDescriptor("USD", newRate("2023-10-03", 4.35)))
+
+	// then
+	assert.Equal(t, "USD", receive(t, c).Currency)
+	assert.Empty(t, c.events)
+}
+
+func TestShouldDropSlowClientWithoutBlocking(t *testing.T) {
+	// given
+	sut := NewBroadcaster(WithClientBuffer(1))
+	slow, _ := sut.subscribe(nil, 0, false)
+	fast, _ := sut.subscribe(nil, 0, false)
+	firstID := sut.nextID
+
+	// when
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61)))
+	receive(t, fast)
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-04", 4.62)))
+
+	// then
+	assert.Equal(t, firstID+1, receive(t, fast).ID)
+	select {
+	case <-slow.dropped:
+	default:
+		t.Fatal("slow client not dropped")
+	}
+	assert.Equal(t, 1, sut.clientCount())
+}
+
+func TestShouldBroadcastAlertsAndPassThemOn(t *testing.T) {
+	// given
+	sinkMock := mocks.NewSink(t)
+	sut := NewBroadcaster()
+	c, _ := sut.subscribe(nil, 0, false)
+	events := []alert.Event{{Key: "threshold/EUR", Name: "threshold", Currency: "EUR", Severity: alert.SeverityWarning,
+		Rate: newRate("2023-10-03", 4.71), Message: "above band"}}
+
+	sinkMock.EXPECT().Notify(mock.Anything, events).Return(nil).Once()
+
+	// when
+	err := sut.AlertSink(sinkMock).Notify(context.Background(), events)
+
+	// then
+	assert.NoError(t, err)
+	e := receive(t, c)
+	assert.Equal(t, EventAlert, e.Type)
+	assert.JSONEq(t, `{"key": "threshold/EUR", "name": "threshold", "currency": "EUR", "date": "2023-10-03", "rate": 4.71,
+		"severity": "warning", "message": "above band", "time": "0001-01-01T00:00:00Z", "resolved": false}`, string(e.Data))
+}
+
+func waitForClients(t *testing.T, sut *Broadcaster, n int) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return sut.clientCount() == n }, time.Second, time.Millisecond)
+}
+
+func readSSEEvents(t *testing.T, body io.Reader, n int) []string {
+	t.Helper()
+	scanner := bufio.NewScanner(body)
+	var (
+		events  []string
+		current []string
+	)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" && len(current) > 0:
+			events = append(events, strings.Join(current, "\n"))
+			current = nil
+		case line != "" && !strings.HasPrefix(line, "retry:") && !strings.HasPrefix(line, ":"):
+			current = append(current, line)
+		}
+	}
+	return events
+}
+
+func TestShouldStreamEventsOverSSE(t *testing.T) {
+	// given
+	sut := NewBroadcaster()
+	server := httptest.NewServer(sut)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "?currency=eur")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	waitForClients(t, sut, 1)
+	firstID := sut.nextID
+
+	// when
+	sut.Process(context.Background(), newRatesDescriptor("USD", newRate("2023-10-03", 4.35)))
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61)))
+
+	// then
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, []string{
+		fmt.Sprintf("id: %d\nevent: rate\ndata: {\"currency\":\"EUR\",\"source\":\"nbp-eur\",\"date\":\"2023-10-03\",\"value\":4.61}", firstID+1),
+	}, readSSEEvents(t, resp.Body, 1))
+}
+
+func TestShouldReplayEventsAfterLastEventID(t *testing.T) {
+	// given
+	sut := NewBroadcaster()
+	server := httptest.NewServer(sut)
+	defer server.Close()
+	firstID := sut.nextID
+	for i, d := range []string{"2023-10-02", "2023-10-03", "2023-10-04"} {
+		sut.Process(context.Background(), newRatesDescriptor("EUR", newRate(d, 4.6+float64(i)/100)))
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(firstID, 10))
+
+	// when
+	resp, err := http.DefaultClient.Do(req)
+
+	// then
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	events := readSSEEvents(t, resp.Body, 2)
+	assert.Len(t, events, 2)
+	assert.True(t, strings.HasPrefix(events[0], fmt.Sprintf("id: %d\n", firstID+1)))
+	assert.True(t, strings.HasPrefix(events[1], fmt.Sprintf("id: %d\n", firstID+2)))
+}
+
+func TestShouldReplayAllEventsToClientOfEarlierRun(t *testing.T) {
+	// given
+	earlier := NewBroadcaster()
+	earlier.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-02", 4.6)))
+	earlier.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61)))
+	lastID := earlier.nextID - 1
+	sut := NewBroadcaster()
+	sut.nextID = firstID(time.Now().Add(time.Millisecond))
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-04", 4.62)))
+
+	// when
+	_, missed := sut.subscribe(nil, lastID, true)
+	_, future := sut.subscribe(nil, sut.nextID+100, true)
+
+	// then
+	assert.Greater(t, sut.nextID-1, lastID)
+	assert.Len(t, missed, 1)
+	assert.Len(t, future, 1)
+}
+
+func TestShouldStreamEventsOverWebSocket(t *testing.T) {
+	// given
+	sut := NewBroadcaster()
+	server := httptest.NewServer(sut)
+	defer server.Close()
+	firstID := sut.nextID
+	sut.Process(context.Background(), newRatesDescriptor("EUR", newRate("2023-10-03", 4.61)))
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	defer conn.Close()
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /?last_event_id=0 HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: %s\r\n\r\n", key)
+	reader := bufio.NewReader(conn)
+
+	// when
+	resp, errHandshake := http.ReadResponse(reader, nil)
+	opcode, payload := readServerFrame(t, reader)
+	conn.Write(clientFrame(opClose, closePayload(1000, "")))
+	closeOpcode, _ := readServerFrame(t, reader)
+
+	// then
+	assert.NoError(t, errHandshake)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, byte(opText), opcode)
+	var msg webSocketMessage
+	assert.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, firstID, msg.ID)
+	assert.Equal(t, EventRate, msg.Event)
+	assert.Equal(t, byte(opClose), closeOpcode)
+	waitForClients(t, sut, 0)
+}
+
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	_, err := io.ReadFull(r, header[:])
+	assert.NoError(t, err)
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	assert.NoError(t, err)
+	return header[0] & 0x0F, payload
+}
+
+func clientFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}